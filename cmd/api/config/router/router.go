@@ -2,24 +2,86 @@ package router
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"habitgobackend/cmd/api/resource/auth"
 	"habitgobackend/cmd/api/resource/habit"
 	"habitgobackend/cmd/api/resource/health"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/cmd/api/resource/idempotency"
+	"habitgobackend/cmd/api/resource/scorecard"
+	"habitgobackend/cmd/api/resource/user"
+	"habitgobackend/cmd/config"
 )
 
-func New(database *gorm.DB, validator *validator.Validate) *chi.Mux {
+func New(database *gorm.DB, habitStore habit.HabitStore, validator *validator.Validate, habitsConfig *config.Config,
+	iconStore icon.Store, healthAPI *health.Api) *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
 
-	router.Get("/health", health.HealthCheckHandler)
+	authConfig := habitsConfig.Server.Auth
+	tokens := auth.NewTokens([]byte(authConfig.JwtSecret), authConfig.AccessTokenTTL, authConfig.RefreshTokenTTL)
+	idempotencyKeys := idempotency.New(database, habitsConfig.Idempotency.Window)
+
+	router.Get("/health", healthAPI.HealthCheck)
+	router.Get("/health/live", healthAPI.Live)
+	router.Get("/health/ready", healthAPI.Ready)
 
 	router.Route("/v1", func(router chi.Router) {
-		habitAPI := habit.New(database, validator)
-		router.Get("/habits", habitAPI.GetHabits)
-		router.Post("/habits", habitAPI.CreateHabit)
-		router.Get("/habits/{id}", habitAPI.GetHabit)
-		router.Put("/habits/{id}", habitAPI.UpdateHabit)
-		router.Delete("/habits/{id}", habitAPI.DeleteHabit)
+		authAPI := auth.New(database, tokens, validator)
+		router.Post("/auth/login", authAPI.Login)
+		router.Post("/auth/refresh", authAPI.Refresh)
+
+		userAPI := user.New(database, validator)
+		router.Post("/users", userAPI.CreateUser)
+
+		iconAPI := icon.New(iconStore, habitsConfig.Icons.MaxBytes)
+		router.Get("/icons/{id}", iconAPI.GetIcon)
+
+		router.Group(func(router chi.Router) {
+			router.Use(tokens.Middleware)
+			router.Use(idempotencyKeys.Middleware)
+
+			router.Get("/users/me", userAPI.GetUser)
+			router.Put("/users/me", userAPI.UpdateUser)
+			router.Delete("/users/me", userAPI.DeleteUser)
+
+			router.Post("/icons", iconAPI.UploadIcon)
+
+			habitAPI := habit.New(habitStore, validator, iconStore, habitsConfig.Icons.MaxBytes)
+			router.Get("/habits", habitAPI.GetHabits)
+			router.Post("/habits", habitAPI.CreateHabit)
+			router.Post("/habits:batch", habitAPI.Batch)
+			router.Get("/habits/trash", habitAPI.GetTrash)
+			router.Get("/habits/routine", habitAPI.GetRoutine)
+			router.Get("/habits/at-risk", habitAPI.GetAtRisk)
+			router.Get("/habits/{id}", habitAPI.GetHabit)
+			router.Put("/habits/{id}", habitAPI.UpdateHabit)
+			router.Patch("/habits/{id}", habitAPI.PatchHabit)
+			router.Delete("/habits/{id}", habitAPI.DeleteHabit)
+			router.Post("/habits/{id}/restore", habitAPI.RestoreHabit)
+			router.Post("/habits/{id}/pause", habitAPI.PauseHabit)
+			router.Post("/habits/{id}/archive", habitAPI.ArchiveHabit)
+			router.Post("/habits/{id}/activate", habitAPI.ActivateHabit)
+			router.Get("/habits/{id}/streak", habitAPI.GetStreak)
+			router.Get("/habits/{id}/stack", habitAPI.GetStack)
+			router.Get("/habits/{id}/levels", habitAPI.GetLevels)
+			router.Post("/habits/{id}/level-up", habitAPI.LevelUp)
+			router.Post("/habits/{id}/level-down", habitAPI.LevelDown)
+
+			router.Get("/habits/{id}/completions", habitAPI.GetCompletions)
+			router.Post("/habits/{id}/completions", habitAPI.CreateCompletion)
+			router.Delete("/habits/{id}/completions/{completionId}", habitAPI.DeleteCompletion)
+
+			scorecardAPI := scorecard.New(database, habitStore, validator)
+			router.Get("/scorecard", scorecardAPI.GetScorecard)
+			router.Put("/scorecard/order", scorecardAPI.OrderScorecard)
+			router.Get("/scorecard/summary", scorecardAPI.GetSummary)
+			router.Post("/scorecard/entries", scorecardAPI.CreateEntry)
+			router.Put("/scorecard/entries/{id}", scorecardAPI.UpdateEntry)
+			router.Delete("/scorecard/entries/{id}", scorecardAPI.DeleteEntry)
+		})
 	})
 
 	return router
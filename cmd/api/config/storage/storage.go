@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"habitgobackend/cmd/config"
+	"habitgobackend/migrations"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pressly/goose/v3"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+
+	fmtDBString = "host=%s user=%s password=%s dbname=%s port=%d sslmode=disable"
+	// SQLite only enforces foreign keys, and with them ON DELETE, when they are switched on per connection.
+	sqliteForeignKeys = "_pragma=foreign_keys(1)"
+)
+
+// Open connects to the database selected by the driver. Postgres is set up by running the goose migrations
+// beforehand, SQLite databases are migrated with the SQLite migrations when they are opened. The memory
+// driver is an in-memory SQLite database, which is gone when the server stops.
+func Open(databaseConfig config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	switch databaseConfig.Driver {
+	case DriverPostgres:
+		dbString := fmt.Sprintf(fmtDBString, databaseConfig.Host, databaseConfig.Username,
+			databaseConfig.Password, databaseConfig.DatabaseName, databaseConfig.Port)
+		return gorm.Open(postgres.Open(dbString), gormConfig)
+	case DriverSQLite:
+		return openSQLite(databaseConfig.Path, gormConfig)
+	case DriverMemory:
+		return openSQLite(":memory:", gormConfig)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", databaseConfig.Driver)
+}
+
+// MigrationVersion returns the version of the newest migration of the driver, which the database has to be
+// migrated to.
+func MigrationVersion(driver string) (int64, error) {
+	if driver == DriverPostgres {
+		return migrations.Version(migrations.FS)
+	}
+	sqliteMigrations, err := migrations.SQLite()
+	if err != nil {
+		return 0, err
+	}
+	return migrations.Version(sqliteMigrations)
+}
+
+func openSQLite(path string, gormConfig *gorm.Config) (*gorm.DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	database, err := gorm.Open(sqlite.Open(path+separator+sqliteForeignKeys), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDatabase, err := database.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only allows a single writer, and every new connection to :memory: opens a new empty database.
+	sqlDatabase.SetMaxOpenConns(1)
+
+	sqliteMigrations, err := migrations.SQLite()
+	if err != nil {
+		return nil, err
+	}
+	// The Go migrations registered by the migrations package are Postgres migrations.
+	provider, err := goose.NewProvider(goose.DialectSQLite3, sqlDatabase, sqliteMigrations,
+		goose.WithDisableGlobalRegistry(true))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		return nil, fmt.Errorf("migrating the SQLite database: %w", err)
+	}
+	return database, nil
+}
@@ -1,21 +1,29 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
 	"habitgobackend/cmd/api/config/router"
+	"habitgobackend/cmd/api/config/storage"
 	"habitgobackend/cmd/api/config/validation"
 	_ "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/cmd/api/resource/health"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/cmd/api/resource/idempotency"
 	"habitgobackend/cmd/config"
 	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+	// The time zones of reminders are loaded from the binary, the image has no time zone database.
+	_ "time/tzdata"
 )
 
-const fmtDBString = "host=%s user=%s password=%s dbname=%s port=%d sslmode=disable"
-
 func main() {
 	habitsConfig := config.New()
 	validator := validation.New()
@@ -27,15 +35,27 @@ func main() {
 		logLevel = gormlogger.Error
 	}
 
-	dbString := fmt.Sprintf(fmtDBString, habitsConfig.Database.Host, habitsConfig.Database.Username,
-		habitsConfig.Database.Password, habitsConfig.Database.DatabaseName, habitsConfig.Database.Port)
-	database, err := gorm.Open(postgres.Open(dbString), &gorm.Config{Logger: gormlogger.Default.LogMode(logLevel)})
+	database, err := storage.Open(habitsConfig.Database, &gorm.Config{Logger: gormlogger.Default.LogMode(logLevel)})
 	if err != nil {
 		log.Fatal("DB connection start failure")
 		return
 	}
+	habitStore := habit.NewRepository(database)
 
-	routerConfig := router.New(database, validator)
+	iconStore, err := icon.NewStore(habitsConfig.Icons.Store, habitsConfig.Icons.Directory, database)
+	if err != nil {
+		log.Fatalf("Icon store setup failure: %s", err)
+		return
+	}
+
+	migrationVersion, err := storage.MigrationVersion(habitsConfig.Database.Driver)
+	if err != nil {
+		log.Fatalf("Migration version lookup failure: %s", err)
+		return
+	}
+
+	healthAPI := health.New(database, migrationVersion)
+	routerConfig := router.New(database, habitStore, validator, habitsConfig, iconStore, healthAPI)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", habitsConfig.Server.Port),
@@ -45,9 +65,51 @@ func main() {
 		IdleTimeout:  habitsConfig.Server.TimeoutIdle,
 	}
 
-	log.Println("Starting server " + server.Addr)
-	err = server.ListenAndServe()
-	if err != nil && !errors.Is(err, http.ErrServerClosed) {
-		log.Fatal("Server startup failed")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go idempotency.NewRepository(database).Sweep(ctx, habitsConfig.Idempotency.SweepInterval)
+	go habit.NewPurger(habitStore, habitsConfig.Trash.Retention).Sweep(ctx, habitsConfig.Trash.PurgeInterval)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Starting server " + server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server startup failed")
+		}
+		return
+	case <-ctx.Done():
+	}
+	// A second signal kills the server straight away.
+	stop()
+
+	shutdown(server, database, healthAPI, habitsConfig.Server.Shutdown)
+}
+
+// shutdown fails the health check, gives load balancers the configured delay to notice, then stops accepting
+// connections and waits for in-flight requests before closing the database connections.
+func shutdown(server *http.Server, database *gorm.DB, healthAPI *health.Api, shutdownConfig config.ShutdownConfig) {
+	log.Println("Shutting down server " + server.Addr)
+	healthAPI.Drain()
+	time.Sleep(shutdownConfig.Delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownConfig.Timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server shutdown did not finish in-flight requests: %s", err)
+		server.Close()
+	}
+
+	if sqlDatabase, err := database.DB(); err == nil {
+		if err := sqlDatabase.Close(); err != nil {
+			log.Printf("DB connection close failure: %s", err)
+		}
 	}
+	log.Println("Server stopped")
 }
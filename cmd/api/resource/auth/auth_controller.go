@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/response"
+	"habitgobackend/cmd/api/resource/user"
+	"net/http"
+)
+
+// missingUserHash is compared against when logging in with an unknown email, so that the response time
+// does not reveal which emails are registered.
+var missingUserHash, _ = bcrypt.GenerateFromPassword([]byte("missing user password"), bcrypt.DefaultCost)
+
+type Api struct {
+	userRepository *user.Repository
+	tokens         *Tokens
+	validator      *validator.Validate
+}
+
+func New(db *gorm.DB, tokens *Tokens, validator *validator.Validate) *Api {
+	return &Api{
+		userRepository: user.NewRepository(db),
+		tokens:         tokens,
+		validator:      validator,
+	}
+}
+
+// Login godoc
+//
+//	@summary		Log in
+//	@description	Exchange an email and password for an access and refresh token
+//	@tags			auth
+//	@accept			json
+//	@produce		json
+//	@param			body	body	JsonLogin	true	"JsonLogin"
+//	@success		200	{object}	JsonTokens
+//	@failure		400	{object}	error.Problem
+//	@failure		401	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/auth/login [post]
+func (a *Api) Login(w http.ResponseWriter, r *http.Request) {
+	login := &JsonLogin{}
+	if !response.Decode(w, r, login) {
+		return
+	}
+
+	login.Email = user.NormaliseEmail(login.Email)
+	if err := a.validator.Struct(login); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	existing, err := a.userRepository.GetUserByEmail(login.Email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			e.ServerError(w, r, e.DatabaseConnectionFailed)
+			return
+		}
+		_ = bcrypt.CompareHashAndPassword(missingUserHash, []byte(login.Password))
+		e.Unauthorized(w, r, e.InvalidCredentials)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(existing.PasswordHash), []byte(login.Password)); err != nil {
+		e.Unauthorized(w, r, e.InvalidCredentials)
+		return
+	}
+
+	a.writeTokens(w, r, existing)
+}
+
+// Refresh godoc
+//
+//	@summary		Refresh tokens
+//	@description	Exchange a refresh token for a new access and refresh token
+//	@tags			auth
+//	@accept			json
+//	@produce		json
+//	@param			body	body	JsonRefresh	true	"JsonRefresh"
+//	@success		200	{object}	JsonTokens
+//	@failure		400	{object}	error.Problem
+//	@failure		401	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/auth/refresh [post]
+func (a *Api) Refresh(w http.ResponseWriter, r *http.Request) {
+	refresh := &JsonRefresh{}
+	if !response.Decode(w, r, refresh) {
+		return
+	}
+
+	if err := a.validator.Struct(refresh); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	userID, err := a.tokens.ParseRefreshToken(refresh.RefreshToken)
+	if err != nil {
+		e.Unauthorized(w, r, e.InvalidToken)
+		return
+	}
+
+	// Deleted users keep their unexpired refresh tokens, so the user has to be looked up again.
+	existing, err := a.userRepository.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			e.Unauthorized(w, r, e.InvalidToken)
+			return
+		}
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	a.writeTokens(w, r, existing)
+}
+
+func (a *Api) writeTokens(w http.ResponseWriter, r *http.Request, authenticated *user.User) {
+	tokens, err := a.tokens.Issue(authenticated.ID)
+	if err != nil {
+		e.ServerError(w, r, e.TokenIssueFailure)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusOK, tokens)
+}
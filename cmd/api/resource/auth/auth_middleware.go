@@ -0,0 +1,30 @@
+package auth
+
+import (
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"net/http"
+	"strings"
+)
+
+// Middleware only lets through requests with a valid bearer access token and stores the user it was
+// issued to in the request context.
+func (t *Tokens) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			e.Unauthorized(w, r, e.MissingIdentity)
+			return
+		}
+
+		userID, err := t.ParseAccessToken(strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			e.Unauthorized(w, r, e.InvalidToken)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(identity.WithUserID(r.Context(), userID)))
+	})
+}
@@ -0,0 +1,18 @@
+package auth
+
+type JsonLogin struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type JsonRefresh struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type JsonTokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int `json:"expiresIn"`
+}
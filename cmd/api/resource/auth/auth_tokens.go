@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	issuer           = "habitgobackend"
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// Tokens issues and verifies HMAC signed access and refresh tokens. Both carry the user ID as their
+// subject and are told apart by their typ claim, so a refresh token can never be used as an access token.
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokens(secret []byte, accessTTL time.Duration, refreshTTL time.Duration) *Tokens {
+	return &Tokens{
+		secret:     secret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+func (t *Tokens) Issue(userID uuid.UUID) (JsonTokens, error) {
+	accessToken, err := t.sign(userID, accessTokenType, t.accessTTL)
+	if err != nil {
+		return JsonTokens{}, err
+	}
+
+	refreshToken, err := t.sign(userID, refreshTokenType, t.refreshTTL)
+	if err != nil {
+		return JsonTokens{}, err
+	}
+
+	return JsonTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.accessTTL.Seconds()),
+	}, nil
+}
+
+func (t *Tokens) ParseAccessToken(token string) (uuid.UUID, error) {
+	return t.parse(token, accessTokenType)
+}
+
+func (t *Tokens) ParseRefreshToken(token string) (uuid.UUID, error) {
+	return t.parse(token, refreshTokenType)
+}
+
+func (t *Tokens) sign(userID uuid.UUID, tokenType string, ttl time.Duration) (string, error) {
+	now := t.now()
+	claims := Claims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+func (t *Tokens) parse(token string, tokenType string) (uuid.UUID, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(t.now),
+	)
+	if err != nil || claims.TokenType != tokenType {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil || userID == uuid.Nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return userID, nil
+}
@@ -1,52 +1,167 @@
 package error
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-type Error struct {
-	Error string `json:"error"`
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response. Type identifies the kind of problem and Title is its
+// human readable summary, Errors lists the fields which failed validation.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
-type Errors struct {
-	Errors []string `json:"errors"`
+func newProblem(name string, title string) Problem {
+	return Problem{Type: "/problems/" + name, Title: title}
 }
 
 var (
-	DatabaseConnectionFailed = []byte(`{"error":"Database connection failed"}`)
-	UpdateFailure            = []byte(`{"error":"Update failed"}`)
-	CreateFailure            = []byte(`{"error":"Could not create entity"}`)
-	DeleteFailure            = []byte(`{"error":"Could not delete entity"}`)
-	JsonEncodeFailure        = []byte(`{"error":"Could not encode entity to JSON"}`)
-	JsonDecodeFailure        = []byte(`{"error":"Could not decode entity from JSON"}`)
-	InvalidUrlRequest        = []byte(`{"error":"Invalid request url params"}`)
+	DatabaseConnectionFailed = newProblem("database-connection-failed", "Database connection failed")
+	UpdateFailure            = newProblem("update-failed", "Update failed")
+	CreateFailure            = newProblem("create-failed", "Could not create entity")
+	DeleteFailure            = newProblem("delete-failed", "Could not delete entity")
+	JsonEncodeFailure        = newProblem("json-encode-failed", "Could not encode entity to JSON")
+	JsonDecodeFailure        = newProblem("json-decode-failed", "Could not decode entity from JSON")
+	ValidationFailure        = newProblem("validation-failed", "Request body failed validation")
+	InvalidUrlRequest        = newProblem("invalid-url-params", "Invalid request url params")
+	InvalidQueryParams       = newProblem("invalid-query-params", "Invalid request query params")
+	UnsupportedSchedule      = newProblem("unsupported-schedule", "Habit schedule is not supported")
+	MissingIdentity          = newProblem("missing-identity", "Missing bearer token")
+	InvalidToken             = newProblem("invalid-token", "Invalid or expired token")
+	InvalidCredentials       = newProblem("invalid-credentials", "Invalid email or password")
+	TokenIssueFailure        = newProblem("token-issue-failed", "Could not issue tokens")
+	IconReadFailure          = newProblem("icon-read-failed", "Could not read icon upload")
+	IconTooLarge             = newProblem("icon-too-large", "Icon is too large")
+	UnsupportedIcon          = newProblem("unsupported-icon", "Icon must be a PNG or SVG image")
+	UnknownIcon              = newProblem("unknown-icon", "Icon does not exist")
+	EmailTaken               = newProblem("email-taken", "Email is already registered")
+	HabitNotFound            = newProblem("habit-not-found", "Habit does not exist")
+	CompletionNotFound       = newProblem("completion-not-found", "Completion does not exist")
+	UserNotFound             = newProblem("user-not-found", "User does not exist")
+	IconNotFound             = newProblem("icon-not-found", "Icon does not exist")
+	UnsupportedPatch         = newProblem("unsupported-patch", "Patch must be a JSON Merge Patch or a JSON Patch")
+	InvalidPatch             = newProblem("invalid-patch", "Patch document is invalid")
+	PatchConflict            = newProblem("patch-conflict", "Patch does not apply to the current entity")
+	HabitModified            = newProblem("habit-modified", "Habit was changed since it was read")
+	InvalidTransition        = newProblem("invalid-transition", "Habit cannot move to this status from its current status")
+	RequestTooLarge          = newProblem("request-too-large", "Request body is too large")
+	RequestReadFailure       = newProblem("request-read-failed", "Could not read request body")
+	InvalidIdempotencyKey    = newProblem("invalid-idempotency-key", "Idempotency key must be at most 255 characters")
+	IdempotencyKeyReused     = newProblem("idempotency-key-reused", "Idempotency key was used for a different request")
+	IdempotencyKeyInProgress = newProblem("idempotency-key-in-progress", "Request with this idempotency key is still in progress")
+	BatchRolledBack          = newProblem("batch-rolled-back", "Operation was rolled back because another operation of the batch failed")
+	UnknownAnchor            = newProblem("unknown-anchor", "Anchor habit does not exist")
+	StackCycle               = newProblem("stack-cycle", "Habit cannot be stacked on itself or on a habit stacked on it")
+	EntryNotFound            = newProblem("entry-not-found", "Scorecard entry does not exist")
+	InvalidOrder             = newProblem("invalid-order", "Order must list every entry of the scorecard once")
+	UnknownHabit             = newProblem("unknown-habit", "Linked habit does not exist")
+	LevelOutOfRange          = newProblem("level-out-of-range", "Habit has no level in this direction")
 )
 
-func ServerError(w http.ResponseWriter, reps []byte) {
-	w.WriteHeader(http.StatusInternalServerError)
-	writeResponse(reps, w)
+// WithDetail returns a copy of the problem explaining this occurrence of it.
+func (p Problem) WithDetail(detail string) Problem {
+	p.Detail = detail
+	return p
+}
+
+func ServerError(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusInternalServerError, problem)
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusNotFound, problem)
+}
+
+func BadRequest(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusBadRequest, problem)
+}
+
+func Unauthorized(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusUnauthorized, problem)
+}
+
+func Conflict(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusConflict, problem)
+}
+
+func TooLarge(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusRequestEntityTooLarge, problem)
+}
+
+func UnsupportedMediaType(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusUnsupportedMediaType, problem)
 }
 
-func NotFound(w http.ResponseWriter, reps []byte) {
-	w.WriteHeader(http.StatusNotFound)
-	writeResponse(reps, w)
+func PreconditionFailed(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusPreconditionFailed, problem)
 }
 
-func BadRequest(w http.ResponseWriter, reps []byte) {
-	w.WriteHeader(http.StatusBadRequest)
-	writeResponse(reps, w)
+func Unprocessable(w http.ResponseWriter, r *http.Request, problem Problem) {
+	write(w, r, http.StatusUnprocessableEntity, problem)
 }
 
-func ValidationErrors(w http.ResponseWriter, reps []byte) {
-	w.WriteHeader(http.StatusUnprocessableEntity)
-	writeResponse(reps, w)
+// ValidationErrors reports the fields of the request body which failed validation, err is the error returned
+// by the validator.
+func ValidationErrors(w http.ResponseWriter, r *http.Request, err error) {
+	problem := Validation(err)
+	write(w, r, problem.Status, problem)
 }
 
-func writeResponse(reps []byte, w http.ResponseWriter) {
-	_, err := w.Write(reps)
-	if err != nil {
+// Validation returns the problem ValidationErrors reports err with, for responses made of several problems.
+func Validation(err error) Problem {
+	problem := ValidationFailure
+	problem.Status = http.StatusUnprocessableEntity
+	problem.Errors = FieldErrors(err)
+	return problem
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, problem Problem) {
+	problem.Status = status
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
 		fmt.Printf("Error writing response: %s", err)
-		return
 	}
 }
+
+// Known pairs an error with the status and problem it is reported with.
+type Known struct {
+	Err     error
+	Status  int
+	Problem Problem
+}
+
+// FromError reports the problem of the first known error which err wraps, any other error is a server error
+// reported as fallback.
+func FromError(w http.ResponseWriter, r *http.Request, err error, fallback Problem, known ...Known) {
+	problem := Lookup(err, fallback, known...)
+	write(w, r, problem.Status, problem)
+}
+
+// Lookup returns the problem FromError reports err with, for responses made of several problems.
+func Lookup(err error, fallback Problem, known ...Known) Problem {
+	for _, k := range known {
+		if errors.Is(err, k.Err) {
+			problem := k.Problem
+			problem.Status = k.Status
+			return problem
+		}
+	}
+	fallback.Status = http.StatusInternalServerError
+	return fallback
+}
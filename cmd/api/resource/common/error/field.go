@@ -0,0 +1,104 @@
+package error
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single failed validation rule. Field is the JSON path of the field, Rule and Param
+// are the validator tag which failed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// FieldErrors translates the errors of the validator, other errors have no field details.
+func FieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	fieldErrors := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fieldPath(fieldError),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: fieldMessage(fieldError),
+		})
+	}
+	return fieldErrors
+}
+
+// fieldPath drops the name of the validated struct from the namespace, which is made of JSON names as long as
+// the validator is set up by the validation package.
+func fieldPath(fieldError validator.FieldError) string {
+	_, path, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+	return path
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	param := fieldError.Param()
+
+	switch fieldError.Tag() {
+	case "required", "required_if", "required_unless", "required_without", "required_without_all":
+		return "is required"
+	case "excluded_unless":
+		return "is not allowed here"
+	case "excluded_if":
+		return "is not allowed when " + conditions(param)
+	case "email":
+		return "must be a valid email address"
+	case "hexadecimal":
+		return "must be hexadecimal"
+	case "uuid":
+		return "must be a UUID"
+	case "datetime":
+		return "must have the layout " + param
+	case "unique":
+		return "must not contain duplicates"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "oneofci":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ") + ", in any case"
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", param, unit(fieldError.Kind()))
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", param, unit(fieldError.Kind()))
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", param, unit(fieldError.Kind()))
+	}
+	return fmt.Sprintf("failed the %s rule", fieldError.Tag())
+}
+
+// conditions reads the field and value pairs of a conditional rule like "Kind avoid" as "kind is avoid". The
+// fields are struct fields, named the way clients send them by lowercasing their first letter.
+func conditions(param string) string {
+	words := strings.Fields(param)
+	pairs := make([]string, 0, len(words)/2)
+	for i := 0; i+1 < len(words); i += 2 {
+		pairs = append(pairs, strings.ToLower(words[i][:1])+words[i][1:]+" is "+words[i+1])
+	}
+	return strings.Join(pairs, " and ")
+}
+
+// unit names what a length rule counts for the kind of field.
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items long"
+	}
+	return ""
+}
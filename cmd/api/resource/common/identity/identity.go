@@ -0,0 +1,21 @@
+package identity
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+func WithUserID(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserID returns the ID of the calling user, or uuid.Nil when the request was not authenticated.
+func UserID(ctx context.Context) uuid.UUID {
+	userID, ok := ctx.Value(contextKey{}).(uuid.UUID)
+	if !ok {
+		return uuid.Nil
+	}
+	return userID
+}
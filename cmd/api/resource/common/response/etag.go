@@ -0,0 +1,41 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IfMatch reports whether the request may change the entity with the given etag, which is the case without an
+// If-Match header or when the header is * or lists the etag. Weak etags never match, as RFC 9110 requires
+// a strong comparison for If-Match.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified answers 304 when the If-None-Match header of the request is * or lists the etag, comparing weak
+// etags by their opaque tag. It returns false when nothing has been written.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
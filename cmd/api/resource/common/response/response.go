@@ -0,0 +1,44 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"net/http"
+)
+
+const ContentType = "application/json"
+
+// JSON writes body as JSON with the given status. The body is encoded before anything is written, so a body
+// which cannot be encoded is still reported as a server error instead of a half written response.
+func JSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		e.ServerError(w, r, e.JsonEncodeFailure)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(append(encoded, '\n')); err != nil {
+		fmt.Printf("Error writing response: %s", err)
+	}
+}
+
+// Created answers that the entity with the given ID was created at location.
+func Created(w http.ResponseWriter, location string, id string) {
+	w.Header().Set("Location", location)
+	w.Header().Set(headers.CREATED_ID, id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Decode reads the JSON request body into body, reporting a malformed body as a bad request. It returns false
+// when the response has been written.
+func Decode(w http.ResponseWriter, r *http.Request, body any) bool {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		e.BadRequest(w, r, e.JsonDecodeFailure)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package habit
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+)
+
+// errOperationFailed rolls back the transaction of a failed batch operation, its problem is in the result.
+var errOperationFailed = errors.New("batch operation failed")
+
+// Batch godoc
+//
+//	@summary		Batch habit changes
+//	@description	Create, update and delete habits in one transaction with a result for every operation. An atomic batch is rolled back as a whole when one of its operations fails and answered with the status of that operation
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			Idempotency-Key	header	string		false	"Replays the response of an earlier request with the same key"
+//	@param			body			body	JsonBatch	true	"JsonBatch"
+//	@success		200	{object}	JsonBatchResults
+//	@failure		400	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits:batch [post]
+func (a *Api) Batch(w http.ResponseWriter, r *http.Request) {
+	batch := &JsonBatch{}
+	if !response.Decode(w, r, batch) {
+		return
+	}
+
+	if err := a.validator.Struct(batch); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	userID := identity.UserID(r.Context())
+	// Icons are kept by the icon store, which is not part of the transaction, so they are stored before it
+	// starts. An icon stored for an operation which is rolled back is only left unused.
+	icons := make([]error, len(batch.Operations))
+	for i, operation := range batch.Operations {
+		if operation.Op != OperationDelete && a.validator.Struct(operation) == nil {
+			icons[i] = a.prepareIcon(r.Context(), operation.Habit)
+		}
+	}
+
+	results := make([]JsonBatchResult, len(batch.Operations))
+	err := a.repository.Transaction(func(store HabitStore) error {
+		for i, operation := range batch.Operations {
+			if batch.Atomic {
+				results[i] = a.execute(store, userID, operation, icons[i])
+				if results[i].Problem != nil {
+					return errOperationFailed
+				}
+				continue
+			}
+
+			// Every operation runs in a nested transaction, so a failed operation neither leaves half of
+			// its writes behind nor aborts the transaction of the others.
+			err := store.Transaction(func(store HabitStore) error {
+				results[i] = a.execute(store, userID, operation, icons[i])
+				if results[i].Problem != nil {
+					return errOperationFailed
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errOperationFailed) {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errOperationFailed) {
+		response.JSON(w, r, rollBack(batch.Operations, results), JsonBatchResults{Results: results})
+		return
+	}
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, JsonBatchResults{Committed: true, Results: results})
+}
+
+// execute runs a single operation of a batch against the store of the batch transaction, iconErr is the
+// error of preparing the icon of the operation.
+func (a *Api) execute(store HabitStore, userID uuid.UUID, operation JsonBatchOperation, iconErr error) JsonBatchResult {
+	if err := a.validator.Struct(operation); err != nil {
+		return failedBatchResult(operation.ID, e.Validation(err))
+	}
+
+	var result JsonBatchResult
+	var err error
+	fallback := e.UpdateFailure
+	switch operation.Op {
+	case OperationCreate:
+		result, err = createInBatch(store, userID, operation.Habit, iconErr)
+		fallback = e.CreateFailure
+	case OperationUpdate:
+		result, err = updateInBatch(store, userID, operation, iconErr)
+	case OperationDelete:
+		result, err = deleteInBatch(store, userID, operation)
+		fallback = e.DeleteFailure
+	}
+
+	if err != nil {
+		return failedBatchResult(operation.ID, e.Lookup(err, fallback, knownErrors...))
+	}
+	return result
+}
+
+func createInBatch(store HabitStore, userID uuid.UUID, jsonHabit *JsonHabit, iconErr error) (JsonBatchResult, error) {
+	if iconErr != nil {
+		return JsonBatchResult{}, iconErr
+	}
+
+	habit := jsonHabit.ToHabit()
+	habit.ID = uuid.New()
+	habit.UserID = userID
+
+	if err := CheckAnchor(store, habit, nil); err != nil {
+		return JsonBatchResult{}, err
+	}
+	if _, err := store.CreateHabit(habit); err != nil {
+		return JsonBatchResult{}, err
+	}
+	return newBatchResult(http.StatusCreated, habit), nil
+}
+
+func updateInBatch(store HabitStore, userID uuid.UUID, operation JsonBatchOperation, iconErr error) (JsonBatchResult, error) {
+	// The ID passed validation, it is a UUID.
+	id := uuid.MustParse(operation.ID)
+
+	current, err := store.GetHabit(userID, id)
+	if err != nil {
+		return JsonBatchResult{}, err
+	}
+	if operation.Version != 0 && operation.Version != current.Version {
+		return JsonBatchResult{}, ErrHabitModified
+	}
+
+	if iconErr != nil {
+		return JsonBatchResult{}, iconErr
+	}
+
+	habit := operation.Habit.ToHabit()
+	habit.ID = id
+	habit.UserID = userID
+	habit.Status = current.Status
+	habit.Version = current.Version
+	habit.CreatedAt = current.CreatedAt
+	change := habit.keepLevel(current)
+
+	if err := CheckAnchor(store, habit, current.AnchorID); err != nil {
+		return JsonBatchResult{}, err
+	}
+	rows, err := saveLevelled(store, change, func(store HabitStore) (int64, error) {
+		return store.UpdateHabit(habit)
+	})
+	if err != nil {
+		return JsonBatchResult{}, err
+	}
+	if rows == 0 {
+		return JsonBatchResult{}, ErrHabitNotFound
+	}
+	return newBatchResult(http.StatusOK, habit), nil
+}
+
+func deleteInBatch(store HabitStore, userID uuid.UUID, operation JsonBatchOperation) (JsonBatchResult, error) {
+	rows, err := store.DeleteHabit(userID, uuid.MustParse(operation.ID), operation.Version)
+	if err != nil {
+		return JsonBatchResult{}, err
+	}
+	if rows == 0 {
+		return JsonBatchResult{}, ErrHabitNotFound
+	}
+	return JsonBatchResult{Status: http.StatusOK, ID: operation.ID}, nil
+}
+
+// rollBack marks every operation of a rolled back atomic batch which did not fail itself as rolled back, and
+// returns the status of the operation which failed.
+func rollBack(operations []JsonBatchOperation, results []JsonBatchResult) int {
+	status := http.StatusInternalServerError
+	for i, result := range results {
+		if result.Problem != nil {
+			status = result.Status
+			continue
+		}
+		problem := e.BatchRolledBack
+		problem.Status = http.StatusFailedDependency
+		results[i] = failedBatchResult(operations[i].ID, problem)
+	}
+	return status
+}
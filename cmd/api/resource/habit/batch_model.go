@@ -0,0 +1,54 @@
+package habit
+
+import (
+	e "habitgobackend/cmd/api/resource/common/error"
+)
+
+// The operations of a batch.
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// JsonBatch is a list of habit operations executed in one transaction. An atomic batch is rolled back as a
+// whole when one of its operations fails, otherwise only the failed operations are left out.
+type JsonBatch struct {
+	Atomic     bool                 `json:"atomic"`
+	Operations []JsonBatchOperation `json:"operations" validate:"required,min=1,max=100"`
+}
+
+// JsonBatchOperation creates, updates or deletes a single habit. ID names the habit updated or deleted,
+// a Version only lets the operation change the habit while it has that version.
+type JsonBatchOperation struct {
+	Op      string     `json:"op" validate:"required,oneof=create update delete"`
+	ID      string     `json:"id,omitempty" validate:"required_unless=Op create,omitempty,uuid"`
+	Version int        `json:"version,omitempty" validate:"min=0"`
+	Habit   *JsonHabit `json:"habit,omitempty" validate:"required_unless=Op delete"`
+}
+
+// JsonBatchResult is the outcome of the operation at the same index of the batch, with the stored habit
+// and its ETag for creates and updates or the problem which made the operation fail.
+type JsonBatchResult struct {
+	Status  int        `json:"status"`
+	ID      string     `json:"id,omitempty"`
+	ETag    string     `json:"etag,omitempty"`
+	Habit   *JsonHabit `json:"habit,omitempty"`
+	Problem *e.Problem `json:"problem,omitempty"`
+}
+
+// JsonBatchResults lists the result of every operation of a batch, Committed is false when an atomic
+// batch was rolled back.
+type JsonBatchResults struct {
+	Committed bool              `json:"committed"`
+	Results   []JsonBatchResult `json:"results"`
+}
+
+func newBatchResult(status int, habit *Habit) JsonBatchResult {
+	jsonHabit := habit.ToJson()
+	return JsonBatchResult{Status: status, ID: jsonHabit.ID, ETag: habit.ETag(), Habit: &jsonHabit}
+}
+
+func failedBatchResult(id string, problem e.Problem) JsonBatchResult {
+	return JsonBatchResult{Status: problem.Status, ID: id, Problem: &problem}
+}
@@ -0,0 +1,180 @@
+package habit
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// GetCompletions godoc
+//
+//	@summary		List habit completions
+//	@description	List completions of a habit, optionally limited to an inclusive date range
+//	@tags			completions
+//	@accept			json
+//	@produce		json
+//	@param			id		path	string	true	"Habit ID"
+//	@param			from	query	string	false	"First day of the range (YYYY-MM-DD)"
+//	@param			to		query	string	false	"Last day of the range (YYYY-MM-DD)"
+//	@success		200	{array}		JsonCompletion
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/completions [get]
+func (a *Api) GetCompletions(w http.ResponseWriter, r *http.Request) {
+	habitID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidQueryParams)
+		return
+	}
+
+	if _, err := a.repository.GetHabit(identity.UserID(r.Context()), habitID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	completions, err := a.repository.Completions().GetCompletions(habitID, from, to)
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, completions.ToJson())
+}
+
+// CreateCompletion godoc
+//
+//	@summary		Check in a habit
+//	@description	Record a completion of a habit, completedAt defaults to the current time
+//	@tags			completions
+//	@accept			json
+//	@produce		json
+//	@param			id				path	string			true	"Habit ID"
+//	@param			Idempotency-Key	header	string			false	"Replays the response of an earlier request with the same key"
+//	@param			body			body	JsonCompletion	true	"JsonCompletion"
+//	@success		201
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/completions [post]
+func (a *Api) CreateCompletion(w http.ResponseWriter, r *http.Request) {
+	habitID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	jsonCompletion := &JsonCompletion{}
+	if !response.Decode(w, r, jsonCompletion) {
+		return
+	}
+
+	if err := a.validator.Struct(jsonCompletion); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	if _, err := a.repository.GetHabit(identity.UserID(r.Context()), habitID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	newCompletion := jsonCompletion.ToCompletion()
+	newCompletion.ID = uuid.New()
+	newCompletion.HabitID = habitID
+	if newCompletion.CompletedAt.IsZero() {
+		newCompletion.CompletedAt = time.Now()
+	}
+
+	if _, err := a.repository.Completions().CreateCompletion(newCompletion); err != nil {
+		e.ServerError(w, r, e.CreateFailure)
+		return
+	}
+
+	response.Created(w, "/habits/"+habitID.String()+"/completions/"+newCompletion.ID.String(), newCompletion.ID.String())
+}
+
+// DeleteCompletion godoc
+//
+//	@summary		Delete habit completion
+//	@description	Delete a single completion of a habit
+//	@tags			completions
+//	@accept			json
+//	@produce		json
+//	@param			id				path	string	true	"Habit ID"
+//	@param			completionId	path	string	true	"Completion ID"
+//	@success		200
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/completions/{completionId} [delete]
+func (a *Api) DeleteCompletion(w http.ResponseWriter, r *http.Request) {
+	habitID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "completionId"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	if _, err := a.repository.GetHabit(identity.UserID(r.Context()), habitID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	rows, err := a.repository.Completions().DeleteCompletion(habitID, id)
+	if err != nil {
+		e.ServerError(w, r, e.DeleteFailure)
+		return
+	}
+	if rows == 0 {
+		e.NotFound(w, r, e.CompletionNotFound)
+	}
+}
+
+// parseDateRange reads the optional from and to query params as whole days and returns them as a
+// half-open range, so that the completions of the whole "to" day are included.
+func parseDateRange(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return from, to, err
+		}
+		from = parsed
+	}
+
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return from, to, err
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, errors.New("from must not be after to")
+	}
+
+	return from, to, nil
+}
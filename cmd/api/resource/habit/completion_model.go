@@ -0,0 +1,58 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JsonCompletion is a check-in of a habit, Amount is how much of the target of a measurable habit it
+// covers.
+type JsonCompletion struct {
+	ID          string    `json:"id"`
+	HabitID     string    `json:"habitId"`
+	CompletedAt time.Time `json:"completedAt"`
+	Amount      float64   `json:"amount,omitempty" validate:"min=0"`
+	Note        string    `json:"note" validate:"max=500"`
+}
+
+type Completion struct {
+	ID          uuid.UUID `gorm:"primary_key"`
+	HabitID     uuid.UUID
+	CompletedAt time.Time
+	Amount      float64 `gorm:"not null;default:0"`
+	Note        string
+}
+
+type Completions []*Completion
+
+func (c Completion) ToJson() JsonCompletion {
+	return JsonCompletion{
+		ID:          c.ID.String(),
+		HabitID:     c.HabitID.String(),
+		CompletedAt: c.CompletedAt,
+		Amount:      c.Amount,
+		Note:        c.Note,
+	}
+}
+
+func (c JsonCompletion) ToCompletion() *Completion {
+	id, _ := uuid.Parse(c.ID)
+	habitID, _ := uuid.Parse(c.HabitID)
+
+	return &Completion{
+		ID:          id,
+		HabitID:     habitID,
+		CompletedAt: c.CompletedAt,
+		Amount:      c.Amount,
+		Note:        c.Note,
+	}
+}
+
+func (completions Completions) ToJson() []JsonCompletion {
+	jsonCompletions := make([]JsonCompletion, 0, len(completions))
+	for _, completion := range completions {
+		jsonCompletions = append(jsonCompletions, completion.ToJson())
+	}
+	return jsonCompletions
+}
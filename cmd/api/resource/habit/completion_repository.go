@@ -0,0 +1,68 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CompletionRepository struct {
+	database *gorm.DB
+}
+
+func NewCompletionRepository(database *gorm.DB) *CompletionRepository {
+	return &CompletionRepository{database}
+}
+
+// GetCompletions returns the completions of a habit ordered by completion time. A zero from or to
+// leaves that end of the range open; from is inclusive and to is exclusive.
+func (repository *CompletionRepository) GetCompletions(habitID uuid.UUID, from, to time.Time) (Completions, error) {
+	completions := make([]*Completion, 0)
+
+	query := repository.database.Where("habit_id = ?", habitID)
+	if !from.IsZero() {
+		query = query.Where("completed_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("completed_at < ?", to)
+	}
+
+	if err := query.Order("completed_at").Find(&completions).Error; err != nil {
+		return nil, err
+	}
+	return completions, nil
+}
+
+// GetHabitsCompletions returns the completions of the given habits since from by habit, each ordered by
+// completion time.
+func (repository *CompletionRepository) GetHabitsCompletions(habitIDs []uuid.UUID, from time.Time) (map[uuid.UUID]Completions, error) {
+	completions := make([]*Completion, 0)
+	if len(habitIDs) > 0 {
+		if err := repository.database.
+			Where("habit_id IN ? AND completed_at >= ?", habitIDs, from).
+			Order("completed_at").
+			Find(&completions).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byHabit := make(map[uuid.UUID]Completions, len(habitIDs))
+	for _, completion := range completions {
+		byHabit[completion.HabitID] = append(byHabit[completion.HabitID], completion)
+	}
+	return byHabit, nil
+}
+
+func (repository *CompletionRepository) CreateCompletion(completion *Completion) (*Completion, error) {
+	if err := repository.database.Create(completion).Error; err != nil {
+		return nil, err
+	}
+	return completion, nil
+}
+
+func (repository *CompletionRepository) DeleteCompletion(habitID uuid.UUID, id uuid.UUID) (int64, error) {
+	result := repository.database.Where("id = ? AND habit_id = ?", id, habitID).Delete(&Completion{})
+
+	return result.RowsAffected, result.Error
+}
@@ -1,67 +1,152 @@
 package habit
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	e "habitgobackend/cmd/api/resource/common/error"
-	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"habitgobackend/cmd/api/resource/icon"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
-import "gorm.io/gorm"
+var errUnknownIcon = errors.New("icon does not exist")
+
+// knownErrors are the errors caused by the request rather than the server, with the status they are
+// reported with.
+var knownErrors = []e.Known{
+	{Err: ErrHabitNotFound, Status: http.StatusNotFound, Problem: e.HabitNotFound},
+	{Err: ErrUnknownSchedule, Status: http.StatusUnprocessableEntity, Problem: e.UnsupportedSchedule},
+	{Err: icon.ErrUnsupportedContent, Status: http.StatusUnprocessableEntity, Problem: e.UnsupportedIcon},
+	{Err: errUnknownIcon, Status: http.StatusUnprocessableEntity, Problem: e.UnknownIcon},
+	{Err: icon.ErrTooLarge, Status: http.StatusRequestEntityTooLarge, Problem: e.IconTooLarge},
+	{Err: ErrUnsupportedPatch, Status: http.StatusUnsupportedMediaType, Problem: e.UnsupportedPatch},
+	{Err: ErrInvalidPatch, Status: http.StatusBadRequest, Problem: e.InvalidPatch},
+	{Err: ErrPatchConflict, Status: http.StatusConflict, Problem: e.PatchConflict},
+	{Err: ErrHabitModified, Status: http.StatusPreconditionFailed, Problem: e.HabitModified},
+	{Err: ErrUnknownAnchor, Status: http.StatusUnprocessableEntity, Problem: e.UnknownAnchor},
+	{Err: ErrStackCycle, Status: http.StatusConflict, Problem: e.StackCycle},
+}
 
 type Api struct {
-	repository *Repository
-	validator  *validator.Validate
+	repository   HabitStore
+	riskAssessor *RiskAssessor
+	iconStore    icon.Store
+	iconMaxBytes int64
+	validator    *validator.Validate
 }
 
-func New(db *gorm.DB, validator *validator.Validate) *Api {
+func New(store HabitStore, validator *validator.Validate, iconStore icon.Store, iconMaxBytes int64) *Api {
 	return &Api{
-		repository: NewRepository(db),
-		validator:  validator,
+		repository:   store,
+		riskAssessor: NewRiskAssessor(store),
+		iconStore:    iconStore,
+		iconMaxBytes: iconMaxBytes,
+		validator:    validator,
 	}
 }
 
 // GetHabit godoc
 //
 //	@summary		Get single habit
-//	@description	Get habit by ID
+//	@description	Get habit by ID, the ETag header is the version of the habit to send as If-Match when changing it
 //	@tags			habits
 //	@accept			json
 //	@produce		json
+//	@param			id				path	string	true	"Habit ID"
+//	@param			include			query	string	false	"Comma separated list of streak, progress, risk and reminder to embed in the habit, habits being avoided have no progress"
+//	@param			tz				query	string	false	"IANA time zone of the user the reminder is computed in, UTC by default"
+//	@param			If-None-Match	header	string	false	"ETag of a cached copy of the habit"
 //	@success		200	{object}	JsonHabit
-//	@failure		404 {object}	error.Error
-//	@failure		500	{object}	error.Error
+//	@success		304
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
 //	@router			/habits/{id} [get]
 func (a *Api) GetHabit(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
-	fmt.Printf("Id - %v", id.String())
 	if err != nil {
-		e.BadRequest(w, e.InvalidUrlRequest)
+		e.BadRequest(w, r, e.InvalidUrlRequest)
 		return
 	}
 
-	habit, err := a.repository.GetHabit(id)
-
+	habit, err := a.repository.GetHabit(identity.UserID(r.Context()), id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		e.ServerError(w, e.DatabaseConnectionFailed)
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
 		return
 	}
 
 	jsonHabit := habit.ToJson()
+	include := strings.Split(r.URL.Query().Get("include"), ",")
+	includeStreak, includeProgress := slices.Contains(include, "streak"), slices.Contains(include, "progress")
+	includeRisk, includeReminder := slices.Contains(include, "risk"), slices.Contains(include, "reminder")
 
-	if err := json.NewEncoder(w).Encode(jsonHabit); err != nil {
-		e.ServerError(w, e.JsonEncodeFailure)
+	location, err := userLocation(r)
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidQueryParams)
 		return
 	}
+
+	// The streak, progress, risk and reminder change with completions and time while the version does not,
+	// so a habit including them is never answered as not modified.
+	if !includeStreak && !includeProgress && !includeRisk && !includeReminder &&
+		response.NotModified(w, r, habit.ETag()) {
+		return
+	}
+
+	if includeStreak {
+		streak, err := a.calculateStreak(habit)
+		if err != nil {
+			e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+			return
+		}
+		jsonStreak := streak.ToJson()
+		jsonHabit.Streak = &jsonStreak
+	}
+
+	if includeProgress && habit.Kind != KindAvoid {
+		progress, err := a.calculateProgress(habit)
+		if err != nil {
+			e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+			return
+		}
+		jsonProgress := progress.ToJson()
+		jsonHabit.Progress = &jsonProgress
+	}
+
+	if includeRisk {
+		risks, err := a.riskAssessor.Assess(Habits{habit}, time.Now().UTC())
+		if err != nil {
+			e.ServerError(w, r, e.DatabaseConnectionFailed)
+			return
+		}
+		if risk, ok := risks[habit.ID]; ok {
+			jsonRisk := risk.ToJson()
+			jsonHabit.Risk = &jsonRisk
+		}
+	}
+
+	if includeReminder {
+		reminder, ok, err := habit.NextReminder(time.Now().In(location))
+		if err != nil {
+			e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+			return
+		}
+		if ok {
+			jsonHabit.NextReminder = &reminder
+		}
+	}
+
+	w.Header().Set("ETag", habit.ETag())
+	response.JSON(w, r, http.StatusOK, jsonHabit)
 }
 
 // CreateHabit godoc
@@ -71,146 +156,411 @@ func (a *Api) GetHabit(w http.ResponseWriter, r *http.Request) {
 //	@tags			habits
 //	@accept			json
 //	@produce		json
-//	@param			body	body	JsonHabit	true	"JsonHabit"
+//	@param			Idempotency-Key	header	string		false	"Replays the response of an earlier request with the same key"
+//	@param			body			body	JsonHabit	true	"JsonHabit"
 //	@success		201
-//	@failure		400	{object}	error.Error
-//	@failure		422	{object}	error.Errors
-//	@failure		500	{object}	error.Error
+//	@failure		400	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		413	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
 //	@router			/habits [post]
 func (a *Api) CreateHabit(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Trying to create habit")
 	jsonHabit := &JsonHabit{}
-	if err := json.NewDecoder(r.Body).Decode(jsonHabit); err != nil {
-		e.ServerError(w, e.JsonDecodeFailure)
+	if !response.Decode(w, r, jsonHabit) {
 		return
 	}
 
 	if err := a.validator.Struct(jsonHabit); err != nil {
-		fmt.Println(err)
-		e.ValidationErrors(w, e.CreateFailure)
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	if !a.resolveIcon(w, r, jsonHabit) {
 		return
 	}
 
 	newHabit := jsonHabit.ToHabit()
 	newHabit.ID = uuid.New()
+	newHabit.UserID = identity.UserID(r.Context())
 
-	_, err := a.repository.CreateHabit(newHabit)
+	if err := CheckAnchor(a.repository, newHabit, nil); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
 
-	if err != nil {
-		e.ServerError(w, e.CreateFailure)
+	if _, err := a.repository.CreateHabit(newHabit); err != nil {
+		e.ServerError(w, r, e.CreateFailure)
 		return
 	}
 
-	w.Header().Set("Location", "/habits/"+newHabit.ID.String())
-	w.Header().Set(headers.CREATED_ID, newHabit.ID.String())
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("ETag", newHabit.ETag())
+	response.Created(w, "/habits/"+newHabit.ID.String(), newHabit.ID.String())
 }
 
 // GetHabits godoc
 //
 //	@summary		List habits
-//	@description	List habits
+//	@description	List a page of habits, the nextCursor of the response fetches the following page. Active habits come with their risk of being missed twice
 //	@tags			habits
 //	@accept			json
 //	@produce		json
+//	@param			limit		query	int		false	"Page size, 20 by default and at most 100"
+//	@param			cursor		query	string	false	"nextCursor of the previous page"
+//	@param			sort		query	string	false	"createdAt or description, prefixed with - for descending order"
+//	@param			status		query	string	false	"active by default, paused, archived or all"
+//	@param			modeType	query	string	false	"Only habits with this schedule type, or with the schedule type of this legacy mode type"
+//	@param			search		query	string	false	"Only habits with a description containing this text"
 //	@success		200	{object}	JsonHabits
-//	@failure		500	{object}	error.Error
+//	@failure		400	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
 //	@router			/habits [get]
 func (a *Api) GetHabits(w http.ResponseWriter, r *http.Request) {
-	habits, err := a.repository.GetHabits()
+	query, err := ParseHabitQuery(r)
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidQueryParams)
+		return
+	}
 
+	habits, err := a.repository.GetHabits(identity.UserID(r.Context()), query)
 	if err != nil {
-		e.ServerError(w, e.DatabaseConnectionFailed)
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
 		return
 	}
 
-	if len(habits) == 0 {
-		_, err := fmt.Fprint(w, "[]")
-		if err != nil {
-			return
-		}
+	page := JsonHabits{}
+	if len(habits) > query.Limit {
+		habits = habits[:query.Limit]
+		nextCursor := NewCursor(query, habits[len(habits)-1]).Encode()
+		page.NextCursor = &nextCursor
 	}
+	page.Habits = habits.ToJson()
 
-	if err := json.NewEncoder(w).Encode(habits); err != nil {
-		e.ServerError(w, e.JsonEncodeFailure)
+	risks, err := a.riskAssessor.Assess(habits, time.Now().UTC())
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
 	}
-	w.WriteHeader(http.StatusOK)
+	for i, habit := range habits {
+		if risk, ok := risks[habit.ID]; ok {
+			jsonRisk := risk.ToJson()
+			page.Habits[i].Risk = &jsonRisk
+		}
+	}
+
+	response.JSON(w, r, http.StatusOK, page)
 }
 
 // UpdateHabit godoc
 //
 //	@summary		Update habit
-//	@description	Update habit
+//	@description	Update habit, with an If-Match header only when the habit still has that ETag
 //	@tags			habits
 //	@accept			json
 //	@produce		json
-//	@param			id		path	string		true	"Habit ID"
-//	@param			body	body	JsonHabit	true	"JsonHabit"
+//	@param			id			path	string		true	"Habit ID"
+//	@param			If-Match	header	string		false	"ETag of the habit being replaced"
+//	@param			body		body	JsonHabit	true	"JsonHabit"
 //	@success		200
-//	@failure		400	{object}	error.Error
-//	@failure		404
-//	@failure		422	{object}	error.Errors
-//	@failure		500	{object}	error.Error
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		413	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
 //	@router			/habits/{id} [put]
 func (a *Api) UpdateHabit(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		e.BadRequest(w, e.InvalidUrlRequest)
+		e.BadRequest(w, r, e.InvalidUrlRequest)
 		return
 	}
 
 	jsonHabit := &JsonHabit{}
-	if err := json.NewDecoder(r.Body).Decode(jsonHabit); err != nil {
-		e.ServerError(w, e.JsonDecodeFailure)
+	if !response.Decode(w, r, jsonHabit) {
 		return
 	}
 
 	if err := a.validator.Struct(jsonHabit); err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	current, ok := a.currentHabit(w, r, id)
+	if !ok {
+		return
+	}
+
+	if !a.resolveIcon(w, r, jsonHabit) {
 		return
 	}
 
 	habit := jsonHabit.ToHabit()
 	habit.ID = id
+	habit.UserID = current.UserID
+	habit.Version = current.Version
+	change := habit.keepLevel(current)
 
-	rows, err := a.repository.UpdateHabit(habit)
+	if err := CheckAnchor(a.repository, habit, current.AnchorID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	rows, err := saveLevelled(a.repository, change, func(store HabitStore) (int64, error) {
+		return store.UpdateHabit(habit)
+	})
 	if err != nil {
-		e.ServerError(w, e.UpdateFailure)
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
 		return
 	}
 
 	if rows == 0 {
-		http.Error(w, "Habit not found", http.StatusNotFound)
+		e.NotFound(w, r, e.HabitNotFound)
+		return
+	}
+	w.Header().Set("ETag", habit.ETag())
+}
+
+// PatchHabit godoc
+//
+//	@summary		Patch habit
+//	@description	Change some fields of a habit with a JSON Merge Patch or a JSON Patch, only the patched habit is validated
+//	@tags			habits
+//	@accept			application/merge-patch+json,application/json-patch+json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being patched"
+//	@param			body		body	object	true	"Merge patch of a JsonHabit or JSON Patch operations"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		413	{object}	error.Problem
+//	@failure		415	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id} [patch]
+func (a *Api) PatchHabit(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		e.BadRequest(w, r, e.JsonDecodeFailure)
+		return
+	}
+
+	habit, ok := a.currentHabit(w, r, id)
+	if !ok {
+		return
+	}
+
+	jsonHabit, err := ApplyPatch(habit, r.Header.Get("Content-Type"), patch)
+	if err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+
+	if err := a.validator.Struct(jsonHabit); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	if !a.resolveIcon(w, r, jsonHabit) {
+		return
+	}
+
+	patched := jsonHabit.ToHabit()
+	patched.ID = habit.ID
+	patched.UserID = habit.UserID
+	patched.Status = habit.Status
+	patched.Version = habit.Version
+	patched.CreatedAt = habit.CreatedAt
+	change := patched.keepLevel(habit)
+
+	if err := CheckAnchor(a.repository, patched, habit.AnchorID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	if fields := habit.ChangedFields(patched); len(fields) > 0 {
+		rows, err := saveLevelled(a.repository, change, func(store HabitStore) (int64, error) {
+			return store.PatchHabit(patched, fields)
+		})
+		if err != nil {
+			e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+			return
+		}
+		if rows == 0 {
+			e.NotFound(w, r, e.HabitNotFound)
+			return
+		}
 	}
+
+	w.Header().Set("ETag", patched.ETag())
+	response.JSON(w, r, http.StatusOK, patched.ToJson())
 }
 
 // DeleteHabit godoc
 //
 //	@summary		Delete habit
-//	@description	Delete habit
+//	@description	Move a habit to the trash, with an If-Match header only when the habit still has that ETag
 //	@tags			habits
 //	@accept			json
 //	@produce		json
-//	@param			id	path	string	true	"Habit ID"
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being deleted"
 //	@success		200
-//	@failure		400	{object}	error.Error
-//	@failure		404
-//	@failure		500	{object}	error.Error
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
 //	@router			/habits/{id} [delete]
 func (a *Api) DeleteHabit(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		e.BadRequest(w, e.InvalidUrlRequest)
+		e.BadRequest(w, r, e.InvalidUrlRequest)
 		return
 	}
 
-	rows, err := a.repository.DeleteHabit(id)
+	var version int
+	if r.Header.Get("If-Match") != "" {
+		current, ok := a.currentHabit(w, r, id)
+		if !ok {
+			return
+		}
+		version = current.Version
+	}
+
+	rows, err := a.repository.DeleteHabit(identity.UserID(r.Context()), id, version)
 	if err != nil {
-		e.BadRequest(w, e.DeleteFailure)
+		e.FromError(w, r, err, e.DeleteFailure, knownErrors...)
 		return
 	}
 	if rows == 0 {
-		w.WriteHeader(http.StatusNotFound)
+		e.NotFound(w, r, e.HabitNotFound)
+	}
+}
+
+// GetTrash godoc
+//
+//	@summary		List deleted habits
+//	@description	List the habits in the trash, the most recently deleted first. They are purged once the retention period is over
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@success		200	{object}	JsonHabits
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/trash [get]
+func (a *Api) GetTrash(w http.ResponseWriter, r *http.Request) {
+	habits, err := a.repository.GetTrash(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, JsonHabits{Habits: habits.ToJson()})
+}
+
+// RestoreHabit godoc
+//
+//	@summary		Restore habit
+//	@description	Take a deleted habit out of the trash, together with its completions
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id	path	string	true	"Habit ID"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/restore [post]
+func (a *Api) RestoreHabit(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habit, err := a.repository.RestoreHabit(identity.UserID(r.Context()), id)
+	if err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+
+	w.Header().Set("ETag", habit.ETag())
+	response.JSON(w, r, http.StatusOK, habit.ToJson())
+}
+
+// currentHabit loads the habit a request changes and checks it against the If-Match header of the request.
+// It writes the error response and returns false when the habit cannot be changed.
+func (a *Api) currentHabit(w http.ResponseWriter, r *http.Request, id uuid.UUID) (*Habit, bool) {
+	habit, err := a.repository.GetHabit(identity.UserID(r.Context()), id)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return nil, false
+	}
+	if !response.IfMatch(r, habit.ETag()) {
+		e.PreconditionFailed(w, r, e.HabitModified)
+		return nil, false
+	}
+	return habit, true
+}
+
+// resolveIcon makes sure the habit references a stored icon, see prepareIcon. It writes the error response
+// and returns false when the icon cannot be used.
+func (a *Api) resolveIcon(w http.ResponseWriter, r *http.Request, jsonHabit *JsonHabit) bool {
+	if err := a.prepareIcon(r.Context(), jsonHabit); err != nil {
+		e.FromError(w, r, err, e.CreateFailure, knownErrors...)
+		return false
+	}
+	return true
+}
+
+// prepareIcon makes sure the habit references a stored icon, uploading a legacy inline icon when no icon
+// ID was sent. Inline icons are limited to the size of uploaded icons.
+func (a *Api) prepareIcon(ctx context.Context, jsonHabit *JsonHabit) error {
+	if jsonHabit.IconID == "" {
+		inlineIcon, err := icon.FromDataURL(jsonHabit.IconBase64)
+		if err != nil {
+			return err
+		}
+		if inlineIcon.Size > a.iconMaxBytes {
+			return icon.ErrTooLarge
+		}
+
+		if _, err := a.iconStore.Save(ctx, inlineIcon); err != nil {
+			return err
+		}
+		jsonHabit.IconID = inlineIcon.ID
+		return nil
+	}
+
+	jsonHabit.IconID = strings.ToLower(jsonHabit.IconID)
+	exists, err := a.iconStore.Exists(ctx, jsonHabit.IconID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errUnknownIcon
+	}
+	return nil
+}
+
+// userLocation loads the time zone sent as the tz query param, UTC when none is sent. The time zone of the
+// server is not a time zone of a user.
+func userLocation(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+	if tz == "Local" {
+		return nil, errors.New("unknown time zone Local")
 	}
+	return time.LoadLocation(tz)
 }
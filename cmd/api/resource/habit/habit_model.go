@@ -1,43 +1,265 @@
 package habit
 
-import "github.com/google/uuid"
+import (
+	"habitgobackend/cmd/api/resource/icon"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JsonHabit is the habit contract of the API. IconBase64 and ModeType are kept for old clients: the legacy
+// inline icon is uploaded as a new icon when no iconId is sent, and the legacy free-text schedule is only used
+// when no schedule is sent, it is empty for schedules without a legacy mode type. Status is read only, it is
+// changed by the lifecycle endpoints. Kind is build unless it is sent, habits being avoided have no target.
+// Sentence is the read only intention of the habit as a sentence. AnchorID names the habit this habit is
+// stacked on. Risk is read only, it is computed for active habits. Levels go from the easiest version of the
+// habit to the hardest, Level is read only and changed by the level endpoints. NextReminder is read only, the
+// next time the habit is due at the time of day of its intention, in the time zone the client sent.
 type JsonHabit struct {
-	ID          string `json:"id"`
-	Description string `json:"description" validate:"required"`
-	ColourHex   string `json:"colourHex" validate:"required"`
-	IconBase64  string `json:"iconBase64" validate:"required"`
-	ModeType    string `json:"modeType" validate:"required"`
+	ID           string         `json:"id"`
+	Kind         string         `json:"kind" validate:"omitempty,oneof=build avoid"`
+	Description  string         `json:"description" validate:"required"`
+	ColourHex    string         `json:"colourHex" validate:"required"`
+	IconID       string         `json:"iconId" validate:"required_without=IconBase64,omitempty,len=64,hexadecimal"`
+	IconURL      string         `json:"iconUrl"`
+	IconBase64   string         `json:"iconBase64,omitempty" validate:"required_without=IconID"`
+	Schedule     *JsonSchedule  `json:"schedule" validate:"required_without=ModeType,omitempty"`
+	ModeType     string         `json:"modeType" validate:"required_without=Schedule,omitempty,oneofci=daily weekly monthly yearly"`
+	Target       *JsonTarget    `json:"target,omitempty" validate:"excluded_if=Kind avoid"`
+	Intention    *JsonIntention `json:"intention,omitempty"`
+	AnchorID     string         `json:"anchorId,omitempty" validate:"omitempty,uuid"`
+	Sentence     string         `json:"sentence,omitempty"`
+	TwoMinute    string         `json:"twoMinuteVersion,omitempty" validate:"excluded_if=Kind avoid,max=200"`
+	Levels       []string       `json:"levels,omitempty" validate:"excluded_if=Kind avoid,max=10,dive,required,max=200"`
+	Level        int            `json:"level,omitempty"`
+	Status       string         `json:"status"`
+	Streak       *JsonStreak    `json:"streak,omitempty"`
+	Progress     *JsonProgress  `json:"progress,omitempty"`
+	Risk         *JsonRisk      `json:"risk,omitempty"`
+	NextReminder *time.Time     `json:"nextReminder,omitempty"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	DeletedAt    *time.Time     `json:"deletedAt,omitempty"`
 }
 
 type Habit struct {
 	ID          uuid.UUID `gorm:"primary_key"`
+	UserID      uuid.UUID
+	Kind        string `gorm:"not null;default:build"`
 	Description string
 	ColourHex   string
-	IconBase64  string
-	ModeType    string
+	IconID      string
+	Schedule    Schedule  `gorm:"embedded;embeddedPrefix:schedule_"`
+	Target      Target    `gorm:"embedded;embeddedPrefix:target_"`
+	Intention   Intention `gorm:"embedded;embeddedPrefix:intention_"`
+	// AnchorID is the habit this habit is stacked on, it is done right after its anchor.
+	AnchorID *uuid.UUID `gorm:"type:uuid;index"`
+	// TwoMinute is the two-minute version of the habit, a start so small that it is never skipped.
+	TwoMinute string `gorm:"column:two_minute_version"`
+	// Levels are the versions of the habit from the easiest to the hardest and Level the one being done,
+	// counting from 1, or 0 for habits without levels.
+	Levels Levels `gorm:"not null"`
+	Level  int    `gorm:"not null"`
+	Status string `gorm:"not null;default:active"`
+	// Version counts the writes of the habit, starting at 1, and is the ETag of the habit.
+	Version   int `gorm:"not null;default:1"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// DeletedAt is set while the habit is in the trash, the stores leave trashed habits out of every
+	// lookup other than the trash.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type Habits []*Habit
 
+// The kinds of habits. Completions of a habit being built are the times it was done, completions of a
+// habit being avoided are slips.
+const (
+	KindBuild = "build"
+	KindAvoid = "avoid"
+)
+
+// The lifecycle states of a habit. Only active habits are listed by default, paused and archived habits
+// do not count against their streak.
+const (
+	StatusActive   = "active"
+	StatusPaused   = "paused"
+	StatusArchived = "archived"
+)
+
+// transitions are the states each state can move to.
+var transitions = map[string][]string{
+	StatusActive:   {StatusPaused, StatusArchived},
+	StatusPaused:   {StatusActive, StatusArchived},
+	StatusArchived: {StatusActive},
+}
+
+// The fields of a habit as named by HabitStore.PatchHabit, all but Level and Status can be changed by
+// clients. Levels stores the level together with the levels.
+const (
+	FieldKind        = "Kind"
+	FieldDescription = "Description"
+	FieldColourHex   = "ColourHex"
+	FieldIconID      = "IconID"
+	FieldSchedule    = "Schedule"
+	FieldTarget      = "Target"
+	FieldIntention   = "Intention"
+	FieldAnchor      = "Anchor"
+	FieldTwoMinute   = "TwoMinute"
+	FieldLevels      = "Levels"
+	FieldLevel       = "Level"
+	FieldStatus      = "Status"
+)
+
+var updatableFields = []string{FieldKind, FieldDescription, FieldColourHex, FieldIconID, FieldSchedule, FieldTarget,
+	FieldIntention, FieldAnchor, FieldTwoMinute, FieldLevels}
+
+// ChangedFields lists the fields clients can change in which other differs from the habit.
+func (h Habit) ChangedFields(other *Habit) []string {
+	fields := make([]string, 0, len(updatableFields))
+	if h.Kind != other.Kind {
+		fields = append(fields, FieldKind)
+	}
+	if h.Description != other.Description {
+		fields = append(fields, FieldDescription)
+	}
+	if h.ColourHex != other.ColourHex {
+		fields = append(fields, FieldColourHex)
+	}
+	if h.IconID != other.IconID {
+		fields = append(fields, FieldIconID)
+	}
+	if h.Schedule != other.Schedule {
+		fields = append(fields, FieldSchedule)
+	}
+	if h.Target != other.Target {
+		fields = append(fields, FieldTarget)
+	}
+	if h.Intention != other.Intention {
+		fields = append(fields, FieldIntention)
+	}
+	if !sameAnchor(h.AnchorID, other.AnchorID) {
+		fields = append(fields, FieldAnchor)
+	}
+	if h.TwoMinute != other.TwoMinute {
+		fields = append(fields, FieldTwoMinute)
+	}
+	if !slices.Equal(h.Levels, other.Levels) || h.Level != other.Level {
+		fields = append(fields, FieldLevels)
+	}
+	return fields
+}
+
+// CanTransition reports whether the habit can move from its status to the given one.
+func (h Habit) CanTransition(status string) bool {
+	return slices.Contains(transitions[h.Status], status)
+}
+
+func (h Habit) ETag() string {
+	return strconv.Quote(strconv.Itoa(h.Version))
+}
+
 func (h Habit) ToJson() JsonHabit {
-	return JsonHabit{
+	schedule := h.Schedule.ToJson()
+
+	var iconURL string
+	if h.IconID != "" {
+		iconURL = icon.URL(h.IconID)
+	}
+
+	jsonHabit := JsonHabit{
 		ID:          h.ID.String(),
+		Kind:        h.Kind,
 		Description: h.Description,
 		ColourHex:   h.ColourHex,
-		IconBase64:  h.IconBase64,
-		ModeType:    h.ModeType,
+		IconID:      h.IconID,
+		IconURL:     iconURL,
+		Schedule:    &schedule,
+		ModeType:    h.Schedule.ModeType(),
+		TwoMinute:   h.TwoMinute,
+		Levels:      slices.Clone(h.Levels),
+		Level:       h.Level,
+		Status:      h.Status,
+		CreatedAt:   h.CreatedAt,
+	}
+	if h.Target.Measurable() {
+		target := h.Target.ToJson()
+		jsonHabit.Target = &target
+	}
+	if !h.Intention.IsZero() {
+		intention := h.Intention.ToJson()
+		jsonHabit.Intention = &intention
+		jsonHabit.Sentence = h.Sentence()
+	}
+	if h.AnchorID != nil {
+		jsonHabit.AnchorID = h.AnchorID.String()
 	}
+	if h.DeletedAt.Valid {
+		deletedAt := h.DeletedAt.Time
+		jsonHabit.DeletedAt = &deletedAt
+	}
+	return jsonHabit
 }
 
 func (h JsonHabit) ToHabit() *Habit {
 	id, _ := uuid.Parse(h.ID)
 
+	var schedule Schedule
+	if h.Schedule != nil {
+		schedule = h.Schedule.ToSchedule()
+	} else {
+		schedule, _ = ScheduleFromModeType(h.ModeType)
+	}
+
+	kind := h.Kind
+	if kind == "" {
+		kind = KindBuild
+	}
+
+	var target Target
+	if h.Target != nil {
+		target = h.Target.ToTarget()
+	}
+
+	var intention Intention
+	if h.Intention != nil {
+		intention = h.Intention.ToIntention()
+	}
+
+	var anchorID *uuid.UUID
+	if anchor, err := uuid.Parse(h.AnchorID); err == nil {
+		anchorID = &anchor
+	}
+
+	// A habit starts at its first level, the level of an existing habit is kept by keepLevel.
+	var level int
+	if len(h.Levels) > 0 {
+		level = 1
+	}
+
 	return &Habit{
 		ID:          id,
+		Kind:        kind,
 		Description: h.Description,
 		ColourHex:   h.ColourHex,
-		IconBase64:  h.IconBase64,
-		ModeType:    h.ModeType,
+		IconID:      h.IconID,
+		Schedule:    schedule,
+		Target:      target,
+		Intention:   intention,
+		AnchorID:    anchorID,
+		TwoMinute:   strings.TrimSpace(h.TwoMinute),
+		Levels:      slices.Clone(h.Levels),
+		Level:       level,
+	}
+}
+
+func (habits Habits) ToJson() []JsonHabit {
+	jsonHabits := make([]JsonHabit, 0, len(habits))
+	for _, habit := range habits {
+		jsonHabits = append(jsonHabits, habit.ToJson())
 	}
+	return jsonHabits
 }
@@ -0,0 +1,69 @@
+package habit
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJsonPatch  = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedPatch = errors.New("unsupported patch content type")
+	ErrInvalidPatch     = errors.New("invalid patch document")
+	ErrPatchConflict    = errors.New("patch does not apply to the habit")
+)
+
+// ApplyPatch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), chosen by the content type,
+// to the JSON representation of the habit. The legacy modeType and iconBase64 fields can be patched too,
+// they take over from the schedule and icon ID when those are left untouched.
+func ApplyPatch(habit *Habit, contentType string, patch []byte) (*JsonHabit, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedPatch
+	}
+
+	original := habit.ToJson()
+	original.ModeType = ""
+	document, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case ContentTypeMergePatch:
+		if patched, err = jsonpatch.MergePatch(document, patch); err != nil {
+			return nil, ErrInvalidPatch
+		}
+	case ContentTypeJsonPatch:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+		if patched, err = operations.Apply(document); err != nil {
+			return nil, ErrPatchConflict
+		}
+	default:
+		return nil, ErrUnsupportedPatch
+	}
+
+	jsonHabit := &JsonHabit{}
+	if err := json.Unmarshal(patched, jsonHabit); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	if jsonHabit.ModeType != "" && reflect.DeepEqual(jsonHabit.Schedule, original.Schedule) {
+		jsonHabit.Schedule = nil
+	}
+	if jsonHabit.IconBase64 != "" && jsonHabit.IconID == original.IconID {
+		jsonHabit.IconID = ""
+	}
+	return jsonHabit, nil
+}
@@ -0,0 +1,47 @@
+package habit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger permanently deletes the habits which have been in the trash for longer than the retention. Their
+// completions, pauses and level changes are deleted with them by the database.
+type Purger struct {
+	store     HabitStore
+	retention time.Duration
+}
+
+func NewPurger(store HabitStore, retention time.Duration) *Purger {
+	return &Purger{
+		store:     store,
+		retention: retention,
+	}
+}
+
+// Purge deletes the habits trashed more than the retention before now and returns how many were deleted.
+func (p *Purger) Purge(now time.Time) (int, error) {
+	ids, err := p.store.PurgeHabits(now.Add(-p.retention))
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// Sweep purges the trash every interval until ctx is done.
+func (p *Purger) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := p.Purge(now); err != nil {
+				log.Printf("Habit trash purge failure: %s", err)
+			}
+		}
+	}
+}
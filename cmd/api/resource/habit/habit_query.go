@@ -0,0 +1,157 @@
+package habit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+
+	SortCreatedAt   = "createdAt"
+	SortDescription = "description"
+
+	statusAll = "all"
+)
+
+var ErrInvalidQuery = errors.New("invalid habit query")
+
+var sortColumns = map[string]string{
+	SortCreatedAt:   "created_at",
+	SortDescription: "description",
+}
+
+var scheduleTypes = map[string]bool{
+	ScheduleDaily:        true,
+	ScheduleTimesPerWeek: true,
+	ScheduleWeekdays:     true,
+	ScheduleEveryNDays:   true,
+	ScheduleMonthly:      true,
+}
+
+// HabitQuery selects a single page of a user's habits. After is the position of the last habit of the
+// previous page, nil for the first page. An empty Status selects habits of every status.
+type HabitQuery struct {
+	Limit        int
+	Sort         string
+	Descending   bool
+	After        *Cursor
+	Status       string
+	ScheduleType string
+	Search       string
+}
+
+// Cursor is the position of a habit within a sort order, the ID breaks ties between equal sort values.
+type Cursor struct {
+	Sort        string    `json:"s"`
+	Descending  bool      `json:"r,omitempty"`
+	Description string    `json:"d,omitempty"`
+	CreatedAt   time.Time `json:"c"`
+	ID          uuid.UUID `json:"i"`
+}
+
+type JsonHabits struct {
+	Habits     []JsonHabit `json:"habits"`
+	NextCursor *string     `json:"nextCursor"`
+}
+
+// ParseHabitQuery reads the limit, cursor, sort, status, modeType and search query params. Sort is a field
+// name, prefixed with a minus for a descending order. Status defaults to active, all selects every status.
+// ModeType is a schedule type or a legacy mode type, which selects the type of its schedule, in any case.
+func ParseHabitQuery(r *http.Request) (HabitQuery, error) {
+	values := r.URL.Query()
+	query := HabitQuery{Limit: defaultLimit, Sort: SortCreatedAt, Status: StatusActive}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > maxLimit {
+			return query, ErrInvalidQuery
+		}
+		query.Limit = parsed
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.Sort = strings.TrimPrefix(sort, "-")
+		if _, ok := sortColumns[query.Sort]; !ok {
+			return query, ErrInvalidQuery
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		decoded, err := DecodeCursor(cursor)
+		if err != nil || decoded.Sort != query.Sort || decoded.Descending != query.Descending {
+			return query, ErrInvalidQuery
+		}
+		query.After = decoded
+	}
+
+	if status := values.Get("status"); status != "" {
+		switch {
+		case status == statusAll:
+			query.Status = ""
+		case transitions[status] != nil:
+			query.Status = status
+		default:
+			return query, ErrInvalidQuery
+		}
+	}
+
+	if modeType := strings.ToLower(strings.TrimSpace(values.Get("modeType"))); modeType != "" {
+		if schedule, ok := ScheduleFromModeType(modeType); ok {
+			modeType = schedule.Type
+		}
+		if !scheduleTypes[modeType] {
+			return query, ErrInvalidQuery
+		}
+		query.ScheduleType = modeType
+	}
+
+	query.Search = strings.TrimSpace(values.Get("search"))
+
+	return query, nil
+}
+
+func NewCursor(query HabitQuery, habit *Habit) Cursor {
+	return Cursor{
+		Sort:        query.Sort,
+		Descending:  query.Descending,
+		Description: habit.Description,
+		CreatedAt:   habit.CreatedAt,
+		ID:          habit.ID,
+	}
+}
+
+func (c Cursor) Encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func DecodeCursor(cursor string) (*Cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Cursor{}
+	if err := json.Unmarshal(decoded, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// value is the sort value of the habit the cursor points at.
+func (c Cursor) value() interface{} {
+	if c.Sort == SortDescription {
+		return c.Description
+	}
+	return c.CreatedAt
+}
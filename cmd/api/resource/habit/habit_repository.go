@@ -1,10 +1,35 @@
 package habit
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// fieldColumns are the columns each field of PatchHabit is stored in.
+var fieldColumns = map[string][]string{
+	FieldKind:        {"kind"},
+	FieldDescription: {"description"},
+	FieldColourHex:   {"colour_hex"},
+	FieldIconID:      {"icon_id"},
+	FieldSchedule: {"schedule_type", "schedule_times", "schedule_weekdays", "schedule_interval",
+		"schedule_day_of_month"},
+	FieldTarget:    {"target_value", "target_unit", "target_aggregation"},
+	FieldIntention: {"intention_time", "intention_location", "intention_cue"},
+	FieldAnchor:    {"anchor_id"},
+	FieldTwoMinute: {"two_minute_version"},
+	FieldLevels:    {"levels", "level"},
+	FieldLevel:     {"level"},
+	FieldStatus:    {"status"},
+}
+
 type Repository struct {
 	database *gorm.DB
 }
@@ -13,43 +38,213 @@ func NewRepository(database *gorm.DB) *Repository {
 	return &Repository{database}
 }
 
-func (repository *Repository) GetHabits() (Habits, error) {
+// GetHabits returns a page of the user's habits, with one habit more than the query limit when there is
+// a next page.
+func (repository *Repository) GetHabits(userID uuid.UUID, query HabitQuery) (Habits, error) {
 	habits := make([]*Habit, 0)
-	if err := repository.database.Find(&habits).Error; err != nil {
+
+	column := sortColumns[query.Sort]
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	database := repository.database.Where("user_id = ?", userID)
+	if query.Status != "" {
+		database = database.Where("status = ?", query.Status)
+	}
+	if query.ScheduleType != "" {
+		database = database.Where("schedule_type = ?", query.ScheduleType)
+	}
+	if query.Search != "" {
+		database = database.Where("LOWER(description) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(query.Search))+"%")
+	}
+	if query.After != nil {
+		database = database.Where(
+			fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", column, comparison),
+			query.After.value(), query.After.value(), query.After.ID,
+		)
+	}
+
+	if err := database.
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(query.Limit + 1).
+		Find(&habits).Error; err != nil {
 		return nil, err
 	}
 	return habits, nil
 }
 
 func (repository *Repository) CreateHabit(habit *Habit) (*Habit, error) {
+	habit.Version = 1
+	if habit.Kind == "" {
+		habit.Kind = KindBuild
+	}
+	if habit.Status == "" {
+		habit.Status = StatusActive
+	}
 	if err := repository.database.Create(habit).Error; err != nil {
 		return nil, err
 	}
 	return habit, nil
 }
 
-func (repository *Repository) GetHabit(id uuid.UUID) (*Habit, error) {
+func (repository *Repository) GetAllHabits(userID uuid.UUID) (Habits, error) {
+	habits := make([]*Habit, 0)
+	if err := repository.database.
+		Where("user_id = ?", userID).
+		Order("created_at, id").
+		Find(&habits).Error; err != nil {
+		return nil, err
+	}
+	return habits, nil
+}
+
+func (repository *Repository) GetHabit(userID uuid.UUID, id uuid.UUID) (*Habit, error) {
 	habit := &Habit{}
 	if err := repository.database.
-		Where("id = ?", id).
+		Where("id = ? AND user_id = ?", id, userID).
 		First(&habit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHabitNotFound
+		}
 		return nil, err
 	}
 	return habit, nil
 }
 
 func (repository *Repository) UpdateHabit(habit *Habit) (int64, error) {
+	return repository.PatchHabit(habit, updatableFields)
+}
+
+func (repository *Repository) PatchHabit(habit *Habit, fields []string) (int64, error) {
+	columns := []string{"version"}
+	for _, field := range fields {
+		columns = append(columns, fieldColumns[field]...)
+	}
+
+	updated := *habit
+	updated.Version++
+
 	result := repository.database.
 		Model(&Habit{}).
-		Select("Description", "ColourHex", "IconBase64", "ModeType").
-		Where("id = ?", habit.ID).
-		Updates(habit)
+		Select(columns).
+		Where("id = ? AND user_id = ? AND version = ?", habit.ID, habit.UserID, habit.Version).
+		Updates(&updated)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, repository.modified(habit.UserID, habit.ID)
+	}
 
-	return result.RowsAffected, result.Error
+	habit.Version = updated.Version
+	return result.RowsAffected, nil
 }
 
-func (repository *Repository) DeleteHabit(id uuid.UUID) (int64, error) {
-	result := repository.database.Where("id = ?", id).Delete(&Habit{})
+func (repository *Repository) DeleteHabit(userID uuid.UUID, id uuid.UUID, version int) (int64, error) {
+	database := repository.database.Where("id = ? AND user_id = ?", id, userID)
+	if version != 0 {
+		database = database.Where("version = ?", version)
+	}
+
+	result := database.Delete(&Habit{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 && version != 0 {
+		return 0, repository.modified(userID, id)
+	}
+	return result.RowsAffected, nil
+}
+
+func (repository *Repository) GetTrash(userID uuid.UUID) (Habits, error) {
+	habits := make([]*Habit, 0)
+	if err := repository.database.
+		Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC, id").
+		Find(&habits).Error; err != nil {
+		return nil, err
+	}
+	return habits, nil
+}
+
+func (repository *Repository) RestoreHabit(userID uuid.UUID, id uuid.UUID) (*Habit, error) {
+	result := repository.database.
+		Unscoped().
+		Model(&Habit{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrHabitNotFound
+	}
+	return repository.GetHabit(userID, id)
+}
+
+func (repository *Repository) PurgeHabits(deletedBefore time.Time) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0)
+	// The habits are listed and deleted in one transaction, locking them so that a habit cannot be restored
+	// in between, and the delete repeats the condition so that it never removes a habit out of the trash.
+	err := repository.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Unscoped().
+			Model(&Habit{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		return tx.Unscoped().Where("id IN ? AND deleted_at < ?", ids, deletedBefore).Delete(&Habit{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// modified tells apart why a versioned write matched no rows, it returns ErrHabitModified when the habit
+// still exists and nil when it is gone.
+func (repository *Repository) modified(userID uuid.UUID, id uuid.UUID) error {
+	var count int64
+	if err := repository.database.
+		Model(&Habit{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrHabitModified
+	}
+	return nil
+}
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// Transaction runs fn in a database transaction, nested transactions are savepoints of the outer one.
+func (repository *Repository) Transaction(fn func(store HabitStore) error) error {
+	return repository.database.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepository(tx))
+	})
+}
+
+func (repository *Repository) Completions() CompletionStore {
+	return NewCompletionRepository(repository.database)
+}
+
+func (repository *Repository) Pauses() PauseStore {
+	return NewPauseRepository(repository.database)
+}
 
-	return result.RowsAffected, result.Error
+func (repository *Repository) Levels() LevelStore {
+	return NewLevelRepository(repository.database)
 }
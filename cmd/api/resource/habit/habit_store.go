@@ -0,0 +1,91 @@
+package habit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrHabitNotFound = errors.New("habit not found")
+	ErrHabitModified = errors.New("habit was modified concurrently")
+)
+
+// HabitStore keeps the habits of every user. All lookups are scoped to the owning user, a habit of
+// another user is reported as ErrHabitNotFound or as zero affected rows.
+//
+// Deleting a habit moves it to the trash, where only GetTrash, RestoreHabit and PurgeHabits see it.
+//
+// Writes are optimistic: UpdateHabit and PatchHabit only replace the habit when it still has the version
+// of the given habit, they return ErrHabitModified otherwise and increment the version of the given habit
+// on success. New habits start at version 1.
+type HabitStore interface {
+	GetHabits(userID uuid.UUID, query HabitQuery) (Habits, error)
+	GetHabit(userID uuid.UUID, id uuid.UUID) (*Habit, error)
+	// GetAllHabits returns every habit of the user which is not in the trash, in the order they were created.
+	GetAllHabits(userID uuid.UUID) (Habits, error)
+	CreateHabit(habit *Habit) (*Habit, error)
+	UpdateHabit(habit *Habit) (int64, error)
+	// PatchHabit only writes the given fields of the habit, fields must not be empty.
+	PatchHabit(habit *Habit, fields []string) (int64, error)
+	// DeleteHabit deletes the habit when it has the given version, a version of 0 deletes any version.
+	DeleteHabit(userID uuid.UUID, id uuid.UUID, version int) (int64, error)
+	// GetTrash returns the user's deleted habits, the most recently deleted first.
+	GetTrash(userID uuid.UUID) (Habits, error)
+	// RestoreHabit takes a habit out of the trash, a habit which is not in the trash is reported as
+	// ErrHabitNotFound. Restoring increments the version.
+	RestoreHabit(userID uuid.UUID, id uuid.UUID) (*Habit, error)
+	// PurgeHabits permanently deletes the habits of every user deleted before the given time and returns
+	// their IDs.
+	PurgeHabits(deletedBefore time.Time) ([]uuid.UUID, error)
+	// Transaction runs fn with a store whose writes are only kept when fn returns nil, the error of fn is
+	// returned otherwise. Transactions of the store passed to fn are nested in the outer transaction.
+	Transaction(fn func(store HabitStore) error) error
+	// Completions returns the completions of the habits in the store, the completions of a store passed to
+	// fn by Transaction are written in the transaction.
+	Completions() CompletionStore
+	// Pauses returns the pauses of the habits in the store, written in the transaction like Completions.
+	Pauses() PauseStore
+	// Levels returns the level changes of the habits in the store, written in the transaction like
+	// Completions.
+	Levels() LevelStore
+}
+
+// CompletionStore keeps the completions of habits, it is not scoped to a user so the habit has to be looked
+// up in the HabitStore first.
+type CompletionStore interface {
+	// GetCompletions returns the completions of a habit ordered by completion time. A zero from or to
+	// leaves that end of the range open; from is inclusive and to is exclusive.
+	GetCompletions(habitID uuid.UUID, from, to time.Time) (Completions, error)
+	// GetHabitsCompletions returns the completions of the given habits since from by habit, each ordered by
+	// completion time.
+	GetHabitsCompletions(habitIDs []uuid.UUID, from time.Time) (map[uuid.UUID]Completions, error)
+	CreateCompletion(completion *Completion) (*Completion, error)
+	DeleteCompletion(habitID uuid.UUID, id uuid.UUID) (int64, error)
+}
+
+// PauseStore keeps the pauses of habits, like CompletionStore it is not scoped to a user.
+type PauseStore interface {
+	// GetPauses returns the pauses of a habit ordered by when they started.
+	GetPauses(habitID uuid.UUID) (Pauses, error)
+	// GetHabitsPauses returns the pauses of the given habits by habit, each ordered by when they started.
+	GetHabitsPauses(habitIDs []uuid.UUID) (map[uuid.UUID]Pauses, error)
+	StartPause(pause *Pause) (*Pause, error)
+	// EndPause ends the pause of a habit which is still going on.
+	EndPause(habitID uuid.UUID, endedAt time.Time) (int64, error)
+}
+
+// LevelStore keeps the level changes of habits, like CompletionStore it is not scoped to a user.
+type LevelStore interface {
+	// GetLevelChanges returns the level changes of a habit ordered by when they happened.
+	GetLevelChanges(habitID uuid.UUID) (LevelChanges, error)
+	CreateLevelChange(change *LevelChange) (*LevelChange, error)
+}
+
+var (
+	_ HabitStore      = (*Repository)(nil)
+	_ CompletionStore = (*CompletionRepository)(nil)
+	_ PauseStore      = (*PauseRepository)(nil)
+	_ LevelStore      = (*LevelRepository)(nil)
+)
@@ -0,0 +1,115 @@
+package habit
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const timeOfDayLayout = "15:04"
+
+type JsonIntention struct {
+	Time     string `json:"time,omitempty" validate:"required_without_all=Location Cue,omitempty,datetime=15:04"`
+	Location string `json:"location,omitempty" validate:"max=100"`
+	Cue      string `json:"cue,omitempty" validate:"max=200"`
+}
+
+// Intention is the implementation intention of a habit: "I will [habit] at [time] in [location]", done
+// when the cue happens. Time is a time of day formatted as 15:04, in the time zone of the user, which the
+// API does not store: clients send it with the requests that need it. The zero intention is a habit
+// without one.
+type Intention struct {
+	Time     string
+	Location string
+	Cue      string
+}
+
+func (i Intention) IsZero() bool {
+	return i == Intention{}
+}
+
+func (i Intention) ToJson() JsonIntention {
+	return JsonIntention{
+		Time:     i.Time,
+		Location: i.Location,
+		Cue:      i.Cue,
+	}
+}
+
+func (i JsonIntention) ToIntention() Intention {
+	return Intention{
+		Time:     i.Time,
+		Location: strings.TrimSpace(i.Location),
+		Cue:      strings.TrimSpace(i.Cue),
+	}
+}
+
+// Sentence states the intention of the habit, like "When I pour my morning coffee, I will read at 07:30
+// in the kitchen." It is empty for habits without an intention.
+func (h Habit) Sentence() string {
+	if h.Intention.IsZero() {
+		return ""
+	}
+
+	var sentence strings.Builder
+	if h.Intention.Cue != "" {
+		sentence.WriteString("When " + h.Intention.Cue + ", ")
+	}
+	if h.Kind == KindAvoid {
+		sentence.WriteString("I will not " + sentenceCase(h.Description))
+	} else {
+		sentence.WriteString("I will " + sentenceCase(h.Description))
+	}
+	if h.Intention.Time != "" {
+		sentence.WriteString(" at " + h.Intention.Time)
+	}
+	if h.Intention.Location != "" {
+		sentence.WriteString(" in " + h.Intention.Location)
+	}
+	sentence.WriteString(".")
+	return sentence.String()
+}
+
+// sentenceCase lowercases the first letter of a description to continue a sentence with it, leaving
+// acronyms like "TV" as they are.
+func sentenceCase(description string) string {
+	word, _, _ := strings.Cut(description, " ")
+	if len(word) > 1 && strings.ToUpper(word) == word {
+		return description
+	}
+	first, size := utf8.DecodeRuneInString(description)
+	return string(unicode.ToLower(first)) + description[size:]
+}
+
+// NextReminder returns the first time after the given one at which the habit is meant to be done, at the
+// time of day of its intention in the location of after. Reminders fall on every day for daily and times
+// per week schedules and on the first day of every period of the other schedules, every n days schedules
+// counting the same blocks as the streak, see periodAnchor. It returns false for habits without a time of
+// day.
+func (h Habit) NextReminder(after time.Time) (time.Time, bool, error) {
+	if h.Intention.Time == "" {
+		return time.Time{}, false, nil
+	}
+	timeOfDay, err := time.Parse(timeOfDayLayout, h.Intention.Time)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	period, err := h.period(nil, after)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if h.Schedule.Type == ScheduleDaily || h.Schedule.Type == ScheduleTimesPerWeek {
+		period.start = startOfDay
+		period.next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	}
+
+	for day := period.start(after); ; day = period.next(day) {
+		reminder := time.Date(day.Year(), day.Month(), day.Day(), timeOfDay.Hour(), timeOfDay.Minute(), 0, 0,
+			after.Location())
+		if reminder.After(after) {
+			return reminder, true, nil
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package habit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"time"
+)
+
+// LevelUp godoc
+//
+//	@summary		Level up habit
+//	@description	Move a habit to the next harder of its levels
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being levelled up"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/level-up [post]
+func (a *Api) LevelUp(w http.ResponseWriter, r *http.Request) {
+	a.changeLevel(w, r, 1)
+}
+
+// LevelDown godoc
+//
+//	@summary		Level down habit
+//	@description	Move a habit back to the next easier of its levels
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being levelled down"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/level-down [post]
+func (a *Api) LevelDown(w http.ResponseWriter, r *http.Request) {
+	a.changeLevel(w, r, -1)
+}
+
+// GetLevels godoc
+//
+//	@summary		Get habit levels
+//	@description	Get the level changes of a habit and its completions by level, to see when it was ready to level up
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id	path	string	true	"Habit ID"
+//	@success		200	{object}	JsonLevels
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/levels [get]
+func (a *Api) GetLevels(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habit, err := a.repository.GetHabit(identity.UserID(r.Context()), id)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	changes, err := a.repository.Levels().GetLevelChanges(habit.ID)
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	stats, err := a.calculateLevelStats(habit, changes)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	levels := JsonLevels{TwoMinute: habit.TwoMinute, Level: habit.Level,
+		Levels: make([]JsonLevelStats, 0, len(stats)), Changes: changes.ToJson()}
+	for _, s := range stats {
+		levels.Levels = append(levels.Levels, s.ToJson())
+	}
+	response.JSON(w, r, http.StatusOK, levels)
+}
+
+// changeLevel moves a habit the given number of levels up, or down when it is negative, and records the
+// level change.
+func (a *Api) changeLevel(w http.ResponseWriter, r *http.Request, step int) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habit, ok := a.currentHabit(w, r, id)
+	if !ok {
+		return
+	}
+
+	previous := habit.Level
+	level := previous + step
+	switch {
+	case len(habit.Levels) == 0:
+		e.Conflict(w, r, e.LevelOutOfRange.WithDetail("The habit has no levels"))
+		return
+	case level > len(habit.Levels):
+		e.Conflict(w, r, e.LevelOutOfRange.WithDetail("The habit is at its hardest level"))
+		return
+	case level < 1:
+		e.Conflict(w, r, e.LevelOutOfRange.WithDetail("The habit is at its easiest level"))
+		return
+	}
+	habit.Level = level
+
+	change := &LevelChange{ID: uuid.New(), HabitID: habit.ID, FromLevel: previous, ToLevel: level,
+		ChangedAt: time.Now().UTC()}
+	rows, err := saveLevelled(a.repository, change, func(store HabitStore) (int64, error) {
+		return store.PatchHabit(habit, []string{FieldLevel})
+	})
+	if err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+	if rows == 0 {
+		e.NotFound(w, r, e.HabitNotFound)
+		return
+	}
+
+	w.Header().Set("ETag", habit.ETag())
+	response.JSON(w, r, http.StatusOK, habit.ToJson())
+}
+
+func (a *Api) calculateLevelStats(habit *Habit, changes LevelChanges) ([]LevelStats, error) {
+	completions, err := a.repository.Completions().GetCompletions(habit.ID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	pauses, err := a.repository.Pauses().GetPauses(habit.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return CalculateLevelStats(habit, changes, completions, pauses, time.Now().UTC())
+}
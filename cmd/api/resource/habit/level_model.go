@@ -0,0 +1,119 @@
+package habit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Levels are the versions of a habit from the easiest to the hardest, stored as a JSON array.
+type Levels []string
+
+func (l Levels) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	value, err := json.Marshal(l)
+	return string(value), err
+}
+
+func (l *Levels) Scan(value any) error {
+	switch value := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		return l.unmarshal([]byte(value))
+	case []byte:
+		return l.unmarshal(value)
+	}
+	return errors.New("levels must be a JSON array")
+}
+
+// unmarshal reads the JSON array of levels, leaving the levels nil when there are none.
+func (l *Levels) unmarshal(value []byte) error {
+	levels := Levels{}
+	if err := json.Unmarshal(value, &levels); err != nil {
+		return err
+	}
+	if len(levels) == 0 {
+		levels = nil
+	}
+	*l = levels
+	return nil
+}
+
+func (Levels) GormDataType() string {
+	return "json"
+}
+
+// keepLevel carries the level of the current version of the habit over to the habit replacing it, as close
+// as the new levels allow. It returns the level change to record when the new levels move the habit to
+// another level, nil when the level is kept.
+func (h *Habit) keepLevel(current *Habit) *LevelChange {
+	h.Level = 0
+	if len(h.Levels) > 0 {
+		h.Level = min(max(current.Level, 1), len(h.Levels))
+	}
+	if h.Level == current.Level {
+		return nil
+	}
+	return &LevelChange{ID: uuid.New(), HabitID: current.ID, FromLevel: current.Level, ToLevel: h.Level,
+		ChangedAt: time.Now().UTC()}
+}
+
+// LevelChange records a habit moving from one level to another.
+type LevelChange struct {
+	ID        uuid.UUID `gorm:"primary_key"`
+	HabitID   uuid.UUID
+	FromLevel int
+	ToLevel   int
+	ChangedAt time.Time
+}
+
+type LevelChanges []*LevelChange
+
+type JsonLevelChange struct {
+	From      int       `json:"from"`
+	To        int       `json:"to"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+func (LevelChange) TableName() string {
+	return "habit_level_changes"
+}
+
+func (c LevelChange) ToJson() JsonLevelChange {
+	return JsonLevelChange{
+		From:      c.FromLevel,
+		To:        c.ToLevel,
+		ChangedAt: c.ChangedAt,
+	}
+}
+
+func (changes LevelChanges) ToJson() []JsonLevelChange {
+	jsonChanges := make([]JsonLevelChange, 0, len(changes))
+	for _, change := range changes {
+		jsonChanges = append(jsonChanges, change.ToJson())
+	}
+	return jsonChanges
+}
+
+// levelAt returns the level the habit was at, at the given time, from its level changes ordered by when
+// they happened. Before the first change the habit was at the level the change started from.
+func (changes LevelChanges) levelAt(current int, at time.Time) int {
+	level := current
+	if len(changes) > 0 {
+		level = changes[0].FromLevel
+	}
+	for _, change := range changes {
+		if change.ChangedAt.After(at) {
+			break
+		}
+		level = change.ToLevel
+	}
+	return level
+}
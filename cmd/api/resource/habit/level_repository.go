@@ -0,0 +1,48 @@
+package habit
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LevelRepository struct {
+	database *gorm.DB
+}
+
+func NewLevelRepository(database *gorm.DB) *LevelRepository {
+	return &LevelRepository{database}
+}
+
+// GetLevelChanges returns the level changes of a habit ordered by when they happened.
+func (repository *LevelRepository) GetLevelChanges(habitID uuid.UUID) (LevelChanges, error) {
+	changes := make([]*LevelChange, 0)
+	if err := repository.database.
+		Where("habit_id = ?", habitID).
+		Order("changed_at").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (repository *LevelRepository) CreateLevelChange(change *LevelChange) (*LevelChange, error) {
+	if err := repository.database.Create(change).Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// saveLevelled writes a habit with write and records its level change in the same transaction, a change of
+// nil is not recorded.
+func saveLevelled(store HabitStore, change *LevelChange, write func(store HabitStore) (int64, error)) (int64, error) {
+	var rows int64
+	err := store.Transaction(func(store HabitStore) error {
+		var err error
+		if rows, err = write(store); err != nil || rows == 0 || change == nil {
+			return err
+		}
+		_, err = store.Levels().CreateLevelChange(change)
+		return err
+	})
+	return rows, err
+}
@@ -0,0 +1,131 @@
+package habit
+
+import "time"
+
+// LevelStats is how a habit went while it was at one of its levels. Since is when the habit first reached
+// the level and Days how many whole days it spent at the level in total, Since is nil for levels the habit
+// never reached. Periods are the periods of the schedule which started at the level, counted like the
+// periods of the completion rate of a streak, and CompletionRate the share of them in which the habit was
+// done.
+type LevelStats struct {
+	Level          int
+	Description    string
+	Since          *time.Time
+	Days           int
+	Completions    int
+	Periods        int
+	CompletionRate float64
+}
+
+type JsonLevelStats struct {
+	Level          int     `json:"level"`
+	Description    string  `json:"description"`
+	Since          *string `json:"since,omitempty"`
+	Days           int     `json:"days"`
+	Completions    int     `json:"completions"`
+	Periods        int     `json:"periods"`
+	CompletionRate float64 `json:"completionRate"`
+}
+
+// JsonLevels is the level history of a habit with its completion statistics by level.
+type JsonLevels struct {
+	TwoMinute string            `json:"twoMinuteVersion,omitempty"`
+	Level     int               `json:"level"`
+	Levels    []JsonLevelStats  `json:"levels"`
+	Changes   []JsonLevelChange `json:"changes"`
+}
+
+func (s LevelStats) ToJson() JsonLevelStats {
+	jsonStats := JsonLevelStats{
+		Level:          s.Level,
+		Description:    s.Description,
+		Days:           s.Days,
+		Completions:    s.Completions,
+		Periods:        s.Periods,
+		CompletionRate: s.CompletionRate,
+	}
+	if s.Since != nil {
+		since := s.Since.Format(dateLayout)
+		jsonStats.Since = &since
+	}
+	return jsonStats
+}
+
+// CalculateLevelStats breaks the completions of a habit down by the level the habit was at when they were
+// done, to show when the habit was ready to level up. Like for CalculateRisk, missing the period the habit
+// was created in and periods in which it was paused are not counted. The changes must be ordered by when
+// they happened, all periods are calculated in the location of now and completions after now are ignored.
+func CalculateLevelStats(habit *Habit, changes LevelChanges, completions Completions, pauses Pauses,
+	now time.Time) ([]LevelStats, error) {
+	created := habit.periodAnchor(completions, now)
+	period, err := habit.Schedule.period(created)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]LevelStats, len(habit.Levels))
+	for i, description := range habit.Levels {
+		stats[i] = LevelStats{Level: i + 1, Description: description}
+	}
+	at := func(level int) *LevelStats {
+		if level < 1 || level > len(stats) {
+			return nil
+		}
+		return &stats[level-1]
+	}
+
+	durations := make([]time.Duration, len(stats))
+	stint := func(level int, from, to time.Time) {
+		if s := at(level); s != nil && to.After(from) {
+			if s.Since == nil {
+				since := from
+				s.Since = &since
+			}
+			durations[level-1] += to.Sub(from)
+		}
+	}
+	from, level := created, changes.levelAt(habit.Level, time.Time{})
+	for _, change := range changes {
+		if change.ChangedAt.After(now) {
+			break
+		}
+		stint(level, from, change.ChangedAt.In(now.Location()))
+		from, level = change.ChangedAt.In(now.Location()), change.ToLevel
+	}
+	stint(level, from, now)
+
+	for _, completion := range completions {
+		if s := at(changes.levelAt(habit.Level, completion.CompletedAt)); s != nil && !completion.CompletedAt.After(now) {
+			s.Completions++
+		}
+	}
+
+	progress := periodProgress(habit.Target, period, completions, now)
+	goal := habit.Target.goal(period)
+
+	first, current := period.start(created), period.start(now)
+	done := make([]int, len(stats))
+	for start := first; !start.After(current); start = period.next(start) {
+		s := at(changes.levelAt(habit.Level, start))
+		if s == nil {
+			continue
+		}
+		if progress[start.Unix()] >= goal {
+			s.Periods++
+			done[s.Level-1]++
+			continue
+		}
+		if start.Equal(current) || start.Equal(first) || pauses.overlaps(start, period.next(start)) {
+			continue
+		}
+		s.Periods++
+	}
+
+	for i := range stats {
+		stats[i].Days = int(durations[i] / (24 * time.Hour))
+		if stats[i].Periods > 0 {
+			stats[i].CompletionRate = float64(done[i]) / float64(stats[i].Periods)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,120 @@
+package habit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"time"
+)
+
+// PauseHabit godoc
+//
+//	@summary		Pause habit
+//	@description	Pause an active habit, periods in which it is paused do not break its streak
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being paused"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/pause [post]
+func (a *Api) PauseHabit(w http.ResponseWriter, r *http.Request) {
+	a.transition(w, r, StatusPaused)
+}
+
+// ArchiveHabit godoc
+//
+//	@summary		Archive habit
+//	@description	Retire an active or paused habit, archived habits keep their history but are not listed by default
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being archived"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/archive [post]
+func (a *Api) ArchiveHabit(w http.ResponseWriter, r *http.Request) {
+	a.transition(w, r, StatusArchived)
+}
+
+// ActivateHabit godoc
+//
+//	@summary		Activate habit
+//	@description	Resume a paused habit or bring back an archived one
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id			path	string	true	"Habit ID"
+//	@param			If-Match	header	string	false	"ETag of the habit being activated"
+//	@success		200	{object}	JsonHabit
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		412	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/activate [post]
+func (a *Api) ActivateHabit(w http.ResponseWriter, r *http.Request) {
+	a.transition(w, r, StatusActive)
+}
+
+// transition moves a habit to the given status. A pause starts when an active habit is paused or archived
+// and ends when the habit becomes active again.
+func (a *Api) transition(w http.ResponseWriter, r *http.Request, status string) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habit, ok := a.currentHabit(w, r, id)
+	if !ok {
+		return
+	}
+	if !habit.CanTransition(status) {
+		e.Conflict(w, r, e.InvalidTransition.WithDetail("A "+habit.Status+" habit cannot become "+status))
+		return
+	}
+
+	previous := habit.Status
+	habit.Status = status
+
+	// The status and the pause it starts or ends are written together, a habit is never paused without
+	// its pause being recorded.
+	err = a.repository.Transaction(func(store HabitStore) error {
+		rows, err := store.PatchHabit(habit, []string{FieldStatus})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrHabitNotFound
+		}
+
+		now := time.Now().UTC()
+		switch {
+		case previous == StatusActive:
+			_, err = store.Pauses().StartPause(&Pause{ID: uuid.New(), HabitID: habit.ID, StartedAt: now})
+		case status == StatusActive:
+			_, err = store.Pauses().EndPause(habit.ID, now)
+		}
+		return err
+	})
+	if err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+
+	w.Header().Set("ETag", habit.ETag())
+	response.JSON(w, r, http.StatusOK, habit.ToJson())
+}
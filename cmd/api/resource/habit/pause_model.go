@@ -0,0 +1,31 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pause is a stretch of time in which a habit was paused or archived, EndedAt is nil while it lasts.
+type Pause struct {
+	ID        uuid.UUID `gorm:"primary_key"`
+	HabitID   uuid.UUID
+	StartedAt time.Time
+	EndedAt   *time.Time
+}
+
+type Pauses []*Pause
+
+func (Pause) TableName() string {
+	return "habit_pauses"
+}
+
+// overlaps reports whether the habit was paused at any time between from and to, to being exclusive.
+func (pauses Pauses) overlaps(from, to time.Time) bool {
+	for _, pause := range pauses {
+		if pause.StartedAt.Before(to) && (pause.EndedAt == nil || pause.EndedAt.After(from)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PauseRepository struct {
+	database *gorm.DB
+}
+
+func NewPauseRepository(database *gorm.DB) *PauseRepository {
+	return &PauseRepository{database}
+}
+
+// GetPauses returns the pauses of a habit ordered by when they started.
+func (repository *PauseRepository) GetPauses(habitID uuid.UUID) (Pauses, error) {
+	pauses := make([]*Pause, 0)
+	if err := repository.database.
+		Where("habit_id = ?", habitID).
+		Order("started_at").
+		Find(&pauses).Error; err != nil {
+		return nil, err
+	}
+	return pauses, nil
+}
+
+// GetHabitsPauses returns the pauses of the given habits by habit, each ordered by when they started.
+func (repository *PauseRepository) GetHabitsPauses(habitIDs []uuid.UUID) (map[uuid.UUID]Pauses, error) {
+	pauses := make([]*Pause, 0)
+	if len(habitIDs) > 0 {
+		if err := repository.database.
+			Where("habit_id IN ?", habitIDs).
+			Order("started_at").
+			Find(&pauses).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byHabit := make(map[uuid.UUID]Pauses, len(habitIDs))
+	for _, pause := range pauses {
+		byHabit[pause.HabitID] = append(byHabit[pause.HabitID], pause)
+	}
+	return byHabit, nil
+}
+
+func (repository *PauseRepository) StartPause(pause *Pause) (*Pause, error) {
+	if err := repository.database.Create(pause).Error; err != nil {
+		return nil, err
+	}
+	return pause, nil
+}
+
+// EndPause ends the pause of a habit which is still going on.
+func (repository *PauseRepository) EndPause(habitID uuid.UUID, endedAt time.Time) (int64, error) {
+	result := repository.database.
+		Model(&Pause{}).
+		Where("habit_id = ? AND ended_at IS NULL", habitID).
+		Update("ended_at", endedAt)
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,58 @@
+package habit
+
+import "time"
+
+// Progress is how far a habit got in the period of its schedule containing now. Goal is the target value,
+// or the number of completions the schedule requires for habits without a target, and the period ends
+// where the next one starts.
+type Progress struct {
+	Value       float64
+	Goal        float64
+	Unit        string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// JsonProgress is the progress of a habit, PeriodEnd is the last day of the period.
+type JsonProgress struct {
+	Value       float64 `json:"value"`
+	Goal        float64 `json:"goal"`
+	Unit        string  `json:"unit,omitempty"`
+	Done        bool    `json:"done"`
+	PeriodStart string  `json:"periodStart"`
+	PeriodEnd   string  `json:"periodEnd"`
+}
+
+func (p Progress) Done() bool {
+	return p.Value >= p.Goal
+}
+
+func (p Progress) ToJson() JsonProgress {
+	return JsonProgress{
+		Value:       p.Value,
+		Goal:        p.Goal,
+		Unit:        p.Unit,
+		Done:        p.Done(),
+		PeriodStart: p.PeriodStart.Format(dateLayout),
+		PeriodEnd:   p.PeriodEnd.AddDate(0, 0, -1).Format(dateLayout),
+	}
+}
+
+// CalculateProgress computes the progress of a habit in the period containing now, adding up the amounts
+// of its completions in the period as its target aggregates them. Periods are calculated like the
+// periods of CalculateStreak.
+func CalculateProgress(habit *Habit, completions Completions, now time.Time) (Progress, error) {
+	period, err := habit.period(completions, now)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	start := period.start(now)
+	return Progress{
+		Value:       periodProgress(habit.Target, period, completions, now)[start.Unix()],
+		Goal:        habit.Target.goal(period),
+		Unit:        habit.Target.Unit,
+		PeriodStart: start,
+		PeriodEnd:   period.next(start),
+	}, nil
+}
@@ -0,0 +1,224 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The risk statuses of a habit, following the rule to never miss twice: a habit is at risk after missing
+// its last period and lapsed after missing its last two periods in a row.
+const (
+	RiskOnTrack = "on_track"
+	RiskAtRisk  = "at_risk"
+	RiskLapsed  = "lapsed"
+)
+
+// Risk tells whether a habit is about to be missed twice. Missed is the start of the most recent missed
+// period and Due the end of the current period, before which the habit has to be done to stay on track.
+// Due is only set for habits at risk. The zero risk belongs to habits which are not active.
+type Risk struct {
+	Status string
+	Missed *time.Time
+	Due    *time.Time
+}
+
+type JsonRisk struct {
+	Status string     `json:"status"`
+	Missed *string    `json:"missed,omitempty"`
+	Due    *time.Time `json:"due,omitempty"`
+}
+
+func (r Risk) ToJson() JsonRisk {
+	jsonRisk := JsonRisk{
+		Status: r.Status,
+		Due:    r.Due,
+	}
+	if r.Missed != nil {
+		missed := r.Missed.Format(dateLayout)
+		jsonRisk.Missed = &missed
+	}
+	return jsonRisk
+}
+
+// CalculateRisk computes the risk of an active habit from the periods before the current one. A habit done
+// in the current period is on track whatever happened before, otherwise it is at risk when it missed the
+// previous period and lapsed when it also missed the one before. Periods in which the habit was paused for
+// any time are skipped, and missing the period the habit was created in does not count. Habits being
+// avoided miss a day by slipping, their current day only counts once it is over. The completions must
+// cover the periods since RiskWindow.
+func CalculateRisk(habit *Habit, completions Completions, pauses Pauses, now time.Time) (Risk, error) {
+	if habit.Status != StatusActive {
+		return Risk{}, nil
+	}
+
+	period, err := habit.riskPeriod(now)
+	if err != nil {
+		return Risk{}, err
+	}
+
+	progress := periodProgress(habit.Target, period, completions, now)
+	goal := habit.Target.goal(period)
+	done := func(start time.Time) bool {
+		if habit.Kind == KindAvoid {
+			return progress[start.Unix()] == 0
+		}
+		return progress[start.Unix()] >= goal
+	}
+
+	current := period.start(now)
+	risk := Risk{Status: RiskOnTrack}
+	if habit.Kind != KindAvoid && done(current) {
+		return risk, nil
+	}
+
+	created := period.start(habit.CreatedAt.In(now.Location()))
+	missed := 0
+	for _, start := range habit.finishedPeriods(period, pauses, now) {
+		if done(start) || (start.Equal(created) && habit.Kind != KindAvoid) {
+			break
+		}
+		if missed == 0 {
+			missedAt := start
+			risk.Missed = &missedAt
+		}
+		missed++
+	}
+
+	switch missed {
+	case 1:
+		due := period.next(current)
+		risk.Status, risk.Due = RiskAtRisk, &due
+	case 2:
+		risk.Status = RiskLapsed
+	}
+	return risk, nil
+}
+
+// RiskWindow returns the start of the earliest period CalculateRisk looks at, so that only the completions
+// since then need to be loaded.
+func (h Habit) RiskWindow(pauses Pauses, now time.Time) (time.Time, error) {
+	period, err := h.riskPeriod(now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	window := period.start(now)
+	for _, start := range h.finishedPeriods(period, pauses, now) {
+		window = start
+	}
+	return window, nil
+}
+
+// riskPeriod returns the period a miss is counted in, a day for habits being avoided.
+func (h Habit) riskPeriod(now time.Time) (period, error) {
+	if h.Kind == KindAvoid {
+		return Schedule{Type: ScheduleDaily}.period(now)
+	}
+	return h.period(nil, now)
+}
+
+// finishedPeriods returns the starts of the last two periods before the current one in which the habit
+// was not paused, most recent first, going back no further than the period the habit was created in.
+// Pauses do not apply to habits being avoided.
+func (h Habit) finishedPeriods(period period, pauses Pauses, now time.Time) []time.Time {
+	created := period.start(h.periodAnchor(nil, now))
+
+	starts := make([]time.Time, 0, 2)
+	for end := period.start(now); len(starts) < 2 && end.After(created); {
+		start := period.start(end.Add(-time.Nanosecond))
+		if h.Kind == KindAvoid || !pauses.overlaps(start, end) {
+			starts = append(starts, start)
+		}
+		end = start
+	}
+	return starts
+}
+
+// HabitRisk is a habit with its risk.
+type HabitRisk struct {
+	Habit *Habit
+	Risk  Risk
+}
+
+// RiskAssessor calculates the risks of habits, loading the completions and pauses of many habits at once.
+// It lists habits with their risk and finds the habits to alert users about.
+type RiskAssessor struct {
+	store HabitStore
+}
+
+func NewRiskAssessor(store HabitStore) *RiskAssessor {
+	return &RiskAssessor{store: store}
+}
+
+// Assess returns the risks of the active habits by their ID. Habits with a schedule which is not supported
+// are left out.
+func (a *RiskAssessor) Assess(habits Habits, now time.Time) (map[uuid.UUID]Risk, error) {
+	ids := make([]uuid.UUID, 0, len(habits))
+	for _, habit := range habits {
+		if habit.Status == StatusActive {
+			ids = append(ids, habit.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return map[uuid.UUID]Risk{}, nil
+	}
+
+	pauses, err := a.store.Pauses().GetHabitsPauses(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	assessed := make(Habits, 0, len(ids))
+	since := now
+	for _, habit := range habits {
+		if habit.Status != StatusActive {
+			continue
+		}
+		window, err := habit.RiskWindow(pauses[habit.ID], now)
+		if err != nil {
+			continue
+		}
+		assessed = append(assessed, habit)
+		if window.Before(since) {
+			since = window
+		}
+	}
+
+	completions, err := a.store.Completions().GetHabitsCompletions(ids, since)
+	if err != nil {
+		return nil, err
+	}
+
+	risks := make(map[uuid.UUID]Risk, len(assessed))
+	for _, habit := range assessed {
+		risk, err := CalculateRisk(habit, completions[habit.ID], pauses[habit.ID], now)
+		if err != nil {
+			continue
+		}
+		risks[habit.ID] = risk
+	}
+	return risks, nil
+}
+
+// AtRisk returns the habits of the user which are at risk, in the order they were created, for alerting
+// the user before they miss them a second time.
+func (a *RiskAssessor) AtRisk(userID uuid.UUID, now time.Time) ([]HabitRisk, error) {
+	habits, err := a.store.GetAllHabits(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	risks, err := a.Assess(habits, now)
+	if err != nil {
+		return nil, err
+	}
+
+	atRisk := make([]HabitRisk, 0)
+	for _, habit := range habits {
+		if risk, ok := risks[habit.ID]; ok && risk.Status == RiskAtRisk {
+			atRisk = append(atRisk, HabitRisk{Habit: habit, Risk: risk})
+		}
+	}
+	return atRisk, nil
+}
@@ -0,0 +1,36 @@
+package habit
+
+import (
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"time"
+)
+
+// GetAtRisk godoc
+//
+//	@summary		Get habits at risk
+//	@description	List the active habits which were missed in their last period and are missed twice in a row unless they are done in the current one, with their risk, to remind the user in time
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@success		200	{object}	JsonHabits
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/at-risk [get]
+func (a *Api) GetAtRisk(w http.ResponseWriter, r *http.Request) {
+	atRisk, err := a.riskAssessor.AtRisk(identity.UserID(r.Context()), time.Now().UTC())
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	habits := make([]JsonHabit, 0, len(atRisk))
+	for _, habitRisk := range atRisk {
+		jsonHabit := habitRisk.Habit.ToJson()
+		jsonRisk := habitRisk.Risk.ToJson()
+		jsonHabit.Risk = &jsonRisk
+		habits = append(habits, jsonHabit)
+	}
+	response.JSON(w, r, http.StatusOK, JsonHabits{Habits: habits})
+}
@@ -0,0 +1,218 @@
+package habit
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ScheduleDaily        = "daily"
+	ScheduleTimesPerWeek = "times_per_week"
+	ScheduleWeekdays     = "weekdays"
+	ScheduleEveryNDays   = "every_n_days"
+	ScheduleMonthly      = "monthly"
+)
+
+var ErrUnknownSchedule = errors.New("unknown habit schedule")
+
+type JsonSchedule struct {
+	Type       string   `json:"type" validate:"required,oneof=daily times_per_week weekdays every_n_days monthly"`
+	Times      int      `json:"times,omitempty" validate:"required_if=Type times_per_week,excluded_unless=Type times_per_week,omitempty,min=1,max=7"`
+	Weekdays   []string `json:"weekdays,omitempty" validate:"required_if=Type weekdays,excluded_unless=Type weekdays,omitempty,unique,dive,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Interval   int      `json:"interval,omitempty" validate:"required_if=Type every_n_days,excluded_unless=Type every_n_days,omitempty,min=2,max=366"`
+	DayOfMonth int      `json:"dayOfMonth,omitempty" validate:"required_if=Type monthly,excluded_unless=Type monthly,omitempty,min=1,max=31"`
+}
+
+// Schedule describes how often a habit should be done. Only the fields belonging to Type are set:
+// Times for times_per_week, Weekdays for weekdays, Interval for every_n_days and DayOfMonth for monthly.
+// Weekdays is a bit mask indexed by time.Weekday, so Sunday is the lowest bit.
+type Schedule struct {
+	Type       string
+	Times      int
+	Weekdays   int
+	Interval   int
+	DayOfMonth int
+}
+
+// legacyModeTypes maps the free-text mode types used before schedules were introduced, they are still
+// accepted from and returned to old clients.
+var legacyModeTypes = map[string]Schedule{
+	"daily":   {Type: ScheduleDaily},
+	"weekly":  {Type: ScheduleTimesPerWeek, Times: 1},
+	"monthly": {Type: ScheduleMonthly, DayOfMonth: 1},
+	"yearly":  {Type: ScheduleEveryNDays, Interval: 365},
+}
+
+func ScheduleFromModeType(modeType string) (Schedule, bool) {
+	schedule, ok := legacyModeTypes[strings.ToLower(strings.TrimSpace(modeType))]
+	return schedule, ok
+}
+
+// ModeType returns the legacy mode type of the schedule, or an empty string for schedules which cannot
+// be expressed as one, so that a habit sent back as it was read always passes validation.
+func (s Schedule) ModeType() string {
+	for modeType, schedule := range legacyModeTypes {
+		if schedule == s {
+			return modeType
+		}
+	}
+	return ""
+}
+
+func (s Schedule) ToJson() JsonSchedule {
+	jsonSchedule := JsonSchedule{
+		Type:       s.Type,
+		Times:      s.Times,
+		Interval:   s.Interval,
+		DayOfMonth: s.DayOfMonth,
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if s.Weekdays&(1<<day) != 0 {
+			jsonSchedule.Weekdays = append(jsonSchedule.Weekdays, strings.ToLower(day.String()))
+		}
+	}
+	return jsonSchedule
+}
+
+func (s JsonSchedule) ToSchedule() Schedule {
+	schedule := Schedule{
+		Type:       s.Type,
+		Times:      s.Times,
+		Interval:   s.Interval,
+		DayOfMonth: s.DayOfMonth,
+	}
+	for _, weekday := range s.Weekdays {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(weekday, day.String()) {
+				schedule.Weekdays |= 1 << day
+			}
+		}
+	}
+	return schedule
+}
+
+// period describes the repeating window a schedule is tracked in, start truncates a moment to the
+// beginning of its window, next moves from the beginning of one window to the beginning of the next
+// and required is the number of completions needed within a window.
+type period struct {
+	start    func(time.Time) time.Time
+	next     func(time.Time) time.Time
+	required int
+}
+
+// period returns the tracking window of the habit's schedule, see periodAnchor.
+func (h *Habit) period(completions Completions, now time.Time) (period, error) {
+	return h.Schedule.period(h.periodAnchor(completions, now))
+}
+
+// periodAnchor returns the moment the blocks of every n days schedules are counted from, in the location of
+// now: when the habit was created, or for a habit without a creation time its first completion up to now,
+// or now when it has none either. Streaks, progress, risks, level statistics and reminders all count the
+// same blocks.
+func (h *Habit) periodAnchor(completions Completions, now time.Time) time.Time {
+	if !h.CreatedAt.IsZero() {
+		return h.CreatedAt.In(now.Location())
+	}
+	if first := firstCompletion(completions, now); !first.IsZero() {
+		return first
+	}
+	return now
+}
+
+// period returns the tracking window of the schedule. Every n days schedules are counted in blocks of
+// n days starting on the day of anchor.
+func (s Schedule) period(anchor time.Time) (period, error) {
+	nextDay := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+
+	switch s.Type {
+	case ScheduleDaily:
+		return period{start: startOfDay, next: nextDay, required: 1}, nil
+	case ScheduleTimesPerWeek:
+		return period{
+			start: func(t time.Time) time.Time {
+				day := startOfDay(t)
+				// ISO weeks start on a Monday, time.Weekday starts on a Sunday.
+				return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+			},
+			next:     func(t time.Time) time.Time { return t.AddDate(0, 0, 7) },
+			required: max(s.Times, 1),
+		}, nil
+	case ScheduleWeekdays:
+		if s.Weekdays&0x7f == 0 {
+			return period{}, ErrUnknownSchedule
+		}
+		scheduled := func(t time.Time) bool { return s.Weekdays&(1<<t.Weekday()) != 0 }
+		return period{
+			start: func(t time.Time) time.Time {
+				day := startOfDay(t)
+				for !scheduled(day) {
+					day = day.AddDate(0, 0, -1)
+				}
+				return day
+			},
+			next: func(t time.Time) time.Time {
+				day := nextDay(t)
+				for !scheduled(day) {
+					day = nextDay(day)
+				}
+				return day
+			},
+			required: 1,
+		}, nil
+	case ScheduleEveryNDays:
+		if s.Interval < 1 {
+			return period{}, ErrUnknownSchedule
+		}
+		anchor = startOfDay(anchor)
+		return period{
+			start: func(t time.Time) time.Time {
+				days := daysBetween(anchor, startOfDay(t))
+				offset := days % s.Interval
+				if offset < 0 {
+					offset += s.Interval
+				}
+				return anchor.AddDate(0, 0, days-offset)
+			},
+			next:     func(t time.Time) time.Time { return t.AddDate(0, 0, s.Interval) },
+			required: 1,
+		}, nil
+	case ScheduleMonthly:
+		if s.DayOfMonth < 1 || s.DayOfMonth > 31 {
+			return period{}, ErrUnknownSchedule
+		}
+		return period{
+			start: func(t time.Time) time.Time {
+				start := monthlyOccurrence(t.Year(), t.Month(), s.DayOfMonth, t.Location())
+				if t.Before(start) {
+					start = monthlyOccurrence(t.Year(), t.Month()-1, s.DayOfMonth, t.Location())
+				}
+				return start
+			},
+			next: func(t time.Time) time.Time {
+				return monthlyOccurrence(t.Year(), t.Month()+1, s.DayOfMonth, t.Location())
+			},
+			required: 1,
+		}, nil
+	}
+
+	return period{}, ErrUnknownSchedule
+}
+
+// monthlyOccurrence returns the given day of the month, moved to the last day of the month for months
+// which are too short.
+func monthlyOccurrence(year int, month time.Month, day int, location *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, location).Day()
+	return time.Date(year, month, min(day, lastDay), 0, 0, 0, 0, location)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts calendar days, so that daylight saving changes do not shift the result.
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
@@ -0,0 +1,82 @@
+package habit
+
+import "time"
+
+// Slips summarises the completions of a habit being avoided. PerWeek is the number of slips per week since
+// tracking started and Last is the time of the latest slip.
+type Slips struct {
+	Count   int
+	PerWeek float64
+	Last    *time.Time
+}
+
+type JsonSlips struct {
+	Count   int        `json:"count"`
+	PerWeek float64    `json:"perWeek"`
+	Last    *time.Time `json:"last"`
+}
+
+func (s Slips) ToJson() JsonSlips {
+	return JsonSlips{
+		Count:   s.Count,
+		PerWeek: s.PerWeek,
+		Last:    s.Last,
+	}
+}
+
+// calculateCleanStreak computes the streaks of a habit being avoided, whose completions are slips. A day
+// without slips is clean once it is over, the current streak counts the clean days since the last slip,
+// the longest streak is the longest clean run and LastBroken is the day of the last slip which ended a
+// clean run. CompletionRate is the share of clean days. Tracking starts on the day the habit was created,
+// or on the day of an earlier slip, schedules and pauses do not apply to avoided habits.
+func calculateCleanStreak(habit *Habit, completions Completions, now time.Time) Streak {
+	today := startOfDay(now)
+	start := today
+	if !habit.CreatedAt.IsZero() && habit.CreatedAt.Before(now) {
+		start = startOfDay(habit.CreatedAt.In(now.Location()))
+	}
+	if first := firstCompletion(completions, now); !first.IsZero() && first.Before(start) {
+		start = startOfDay(first)
+	}
+
+	slips := Slips{}
+	slipDays := make(map[int64]bool, len(completions))
+	for _, completion := range completions {
+		if completion.CompletedAt.After(now) {
+			continue
+		}
+		slippedAt := completion.CompletedAt.In(now.Location())
+		slipDays[startOfDay(slippedAt).Unix()] = true
+		slips.Count++
+		if slips.Last == nil || slippedAt.After(*slips.Last) {
+			slips.Last = &slippedAt
+		}
+	}
+
+	streak := Streak{Slips: &slips}
+	clean, tracked := 0, 0
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if slipDays[day.Unix()] {
+			if streak.Current > 0 {
+				lastBroken := day
+				streak.LastBroken = &lastBroken
+			}
+			streak.Current = 0
+			tracked++
+			continue
+		}
+		if day.Equal(today) {
+			break
+		}
+		streak.Current++
+		streak.Longest = max(streak.Longest, streak.Current)
+		clean++
+		tracked++
+	}
+
+	if tracked > 0 {
+		streak.CompletionRate = float64(clean) / float64(tracked)
+	}
+	slips.PerWeek = float64(slips.Count) / (float64(daysBetween(start, today)+1) / 7)
+	return streak
+}
@@ -0,0 +1,158 @@
+package habit
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownAnchor = errors.New("anchor habit not found")
+	ErrStackCycle    = errors.New("habit stack would contain a cycle")
+)
+
+func sameAnchor(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// CheckAnchor makes sure the anchor of the habit is another habit of its user and that stacking the habit
+// on it does not make the habit follow itself. Only a changed anchor is checked, so that habits keep
+// anchors which were moved to the trash since.
+func CheckAnchor(store HabitStore, habit *Habit, previous *uuid.UUID) error {
+	if habit.AnchorID == nil || sameAnchor(habit.AnchorID, previous) {
+		return nil
+	}
+	if *habit.AnchorID == habit.ID {
+		return ErrStackCycle
+	}
+
+	habits, err := store.GetAllHabits(habit.UserID)
+	if err != nil {
+		return err
+	}
+
+	anchors := make(map[uuid.UUID]*uuid.UUID, len(habits))
+	for _, other := range habits {
+		anchors[other.ID] = other.AnchorID
+	}
+	if _, ok := anchors[*habit.AnchorID]; !ok {
+		return ErrUnknownAnchor
+	}
+	anchors[habit.ID] = habit.AnchorID
+
+	// Every habit has a single anchor, so following the anchors from the habit either ends or comes back
+	// to the habit within as many steps as there are habits.
+	for anchor, steps := habit.AnchorID, 0; anchor != nil && steps <= len(anchors); steps++ {
+		if *anchor == habit.ID {
+			return ErrStackCycle
+		}
+		anchor = anchors[*anchor]
+	}
+	return nil
+}
+
+// Stack returns the stack of the habit with the given ID in execution order, a stack being a chain of
+// habits each done right after the habit it is anchored to: "after I [anchor], I will [habit]". It starts
+// with the first habit of the stack, goes through the anchors of the habit to the habit and ends with the
+// habits stacked on it. It returns nil when the habit is not one of habits.
+func Stack(habits Habits, id uuid.UUID) Habits {
+	byID := make(map[uuid.UUID]*Habit, len(habits))
+	for _, habit := range habits {
+		byID[habit.ID] = habit
+	}
+	habit, ok := byID[id]
+	if !ok {
+		return nil
+	}
+
+	anchors := Habits{}
+	seen := map[uuid.UUID]bool{id: true}
+	for anchor := anchorOf(habit, byID); anchor != nil && !seen[anchor.ID]; anchor = anchorOf(anchor, byID) {
+		seen[anchor.ID] = true
+		anchors = append(anchors, anchor)
+	}
+	slices.Reverse(anchors)
+
+	return append(anchors, stackedOn(habit, followers(habits, byID), seen)...)
+}
+
+// Routine returns the habits in execution order: every stack one after the other, ordered by the time of
+// day of their first habit, and the habits stacked on the same anchor ordered by their time of day. Habits
+// without a time of day come last, habits with the same time in the order they were created. A habit whose
+// anchor is not one of habits starts a stack of its own.
+func Routine(habits Habits) Habits {
+	byID := make(map[uuid.UUID]*Habit, len(habits))
+	for _, habit := range habits {
+		byID[habit.ID] = habit
+	}
+	stacked := followers(habits, byID)
+
+	routine := make(Habits, 0, len(habits))
+	seen := make(map[uuid.UUID]bool, len(habits))
+	for _, habit := range stacked[uuid.Nil] {
+		routine = append(routine, stackedOn(habit, stacked, seen)...)
+	}
+	// Habits anchored in a circle, which CheckAnchor prevents, are left over at the end.
+	for _, habit := range habits {
+		if !seen[habit.ID] {
+			routine = append(routine, stackedOn(habit, stacked, seen)...)
+		}
+	}
+	return routine
+}
+
+// anchorOf returns the anchor of the habit when it is one of the given habits.
+func anchorOf(habit *Habit, byID map[uuid.UUID]*Habit) *Habit {
+	if habit.AnchorID == nil {
+		return nil
+	}
+	return byID[*habit.AnchorID]
+}
+
+// followers groups the habits by the habit they are stacked on in execution order, the first habits of
+// every stack are grouped under uuid.Nil.
+func followers(habits Habits, byID map[uuid.UUID]*Habit) map[uuid.UUID]Habits {
+	stacked := make(map[uuid.UUID]Habits, len(habits))
+	for _, habit := range habits {
+		anchorID := uuid.Nil
+		if anchor := anchorOf(habit, byID); anchor != nil {
+			anchorID = anchor.ID
+		}
+		stacked[anchorID] = append(stacked[anchorID], habit)
+	}
+	for _, group := range stacked {
+		slices.SortStableFunc(group, compareExecution)
+	}
+	return stacked
+}
+
+// stackedOn returns the habit followed by every habit stacked on it which was not seen yet, depth first.
+func stackedOn(habit *Habit, stacked map[uuid.UUID]Habits, seen map[uuid.UUID]bool) Habits {
+	seen[habit.ID] = true
+	result := Habits{habit}
+	for _, follower := range stacked[habit.ID] {
+		if !seen[follower.ID] {
+			result = append(result, stackedOn(follower, stacked, seen)...)
+		}
+	}
+	return result
+}
+
+func compareExecution(a, b *Habit) int {
+	if a.Intention.Time != b.Intention.Time {
+		if a.Intention.Time == "" || b.Intention.Time == "" {
+			return cmp.Compare(b.Intention.Time, a.Intention.Time)
+		}
+		return cmp.Compare(a.Intention.Time, b.Intention.Time)
+	}
+	if result := a.CreatedAt.Compare(b.CreatedAt); result != 0 {
+		return result
+	}
+	return strings.Compare(a.ID.String(), b.ID.String())
+}
@@ -0,0 +1,72 @@
+package habit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+)
+
+// GetStack godoc
+//
+//	@summary		Get habit stack
+//	@description	List the stack of a habit in execution order: the habits it is stacked on, the habit and the habits stacked on it
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id	path	string	true	"Habit ID"
+//	@success		200	{object}	JsonHabits
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/stack [get]
+func (a *Api) GetStack(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habits, err := a.repository.GetAllHabits(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	stack := Stack(habits, id)
+	if stack == nil {
+		e.NotFound(w, r, e.HabitNotFound)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, JsonHabits{Habits: stack.ToJson()})
+}
+
+// GetRoutine godoc
+//
+//	@summary		Get routine
+//	@description	List the active habits in execution order, every habit stack one after the other ordered by the intention time of their habits
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@success		200	{object}	JsonHabits
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/routine [get]
+func (a *Api) GetRoutine(w http.ResponseWriter, r *http.Request) {
+	habits, err := a.repository.GetAllHabits(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	active := make(Habits, 0, len(habits))
+	for _, habit := range habits {
+		if habit.Status == StatusActive {
+			active = append(active, habit)
+		}
+	}
+
+	response.JSON(w, r, http.StatusOK, JsonHabits{Habits: Routine(active).ToJson()})
+}
@@ -0,0 +1,122 @@
+package habit
+
+import "time"
+
+// Streak summarises how consistently a habit is done. CompletionRate is the share of the periods since the
+// first completion in which the habit was done, leaving out the current period until it is done. Slips is
+// only set for habits being avoided.
+type Streak struct {
+	Current        int
+	Longest        int
+	LastBroken     *time.Time
+	CompletionRate float64
+	Slips          *Slips
+}
+
+type JsonStreak struct {
+	Current        int        `json:"current"`
+	Longest        int        `json:"longest"`
+	LastBroken     *string    `json:"lastBroken"`
+	CompletionRate float64    `json:"completionRate"`
+	Slips          *JsonSlips `json:"slips,omitempty"`
+}
+
+func (s Streak) ToJson() JsonStreak {
+	jsonStreak := JsonStreak{
+		Current:        s.Current,
+		Longest:        s.Longest,
+		CompletionRate: s.CompletionRate,
+	}
+	if s.LastBroken != nil {
+		lastBroken := s.LastBroken.Format(dateLayout)
+		jsonStreak.LastBroken = &lastBroken
+	}
+	if s.Slips != nil {
+		slips := s.Slips.ToJson()
+		jsonStreak.Slips = &slips
+	}
+	return jsonStreak
+}
+
+// CalculateStreak computes the streaks of a habit from its schedule and target. A period of the schedule
+// counts towards a streak when the completions in it reach the target, or the required number of
+// completions for habits without a target. The period containing now is still in progress, so missing it
+// does not break the current streak, and neither does missing a period in which the habit was paused for
+// any time. LastBroken is the start of the most recent missed period which ended a streak. All periods are
+// calculated in the location of now and completions after now are ignored. Habits being avoided have
+// streaks of clean days instead, see calculateCleanStreak.
+func CalculateStreak(habit *Habit, completions Completions, pauses Pauses, now time.Time) (Streak, error) {
+	if habit.Kind == KindAvoid {
+		return calculateCleanStreak(habit, completions, now), nil
+	}
+
+	first := firstCompletion(completions, now)
+
+	streak := Streak{}
+	if first.IsZero() {
+		_, err := habit.Schedule.period(now)
+		return streak, err
+	}
+
+	period, err := habit.period(completions, now)
+	if err != nil {
+		return streak, err
+	}
+
+	progress := periodProgress(habit.Target, period, completions, now)
+	goal := habit.Target.goal(period)
+
+	current := period.start(now)
+	done, tracked := 0, 0
+	for start := period.start(first); !start.After(current); start = period.next(start) {
+		if progress[start.Unix()] >= goal {
+			streak.Current++
+			streak.Longest = max(streak.Longest, streak.Current)
+			done++
+			tracked++
+			continue
+		}
+		if start.Equal(current) {
+			break
+		}
+		if pauses.overlaps(start, period.next(start)) {
+			continue
+		}
+		tracked++
+		if streak.Current > 0 {
+			lastBroken := start
+			streak.LastBroken = &lastBroken
+		}
+		streak.Current = 0
+	}
+
+	if tracked > 0 {
+		streak.CompletionRate = float64(done) / float64(tracked)
+	}
+	return streak, nil
+}
+
+// firstCompletion returns the time of the earliest completion up to now in the location of now, or the zero
+// time when there is none.
+func firstCompletion(completions Completions, now time.Time) time.Time {
+	var first time.Time
+	for _, completion := range completions {
+		if !completion.CompletedAt.After(now) && (first.IsZero() || completion.CompletedAt.Before(first)) {
+			first = completion.CompletedAt.In(now.Location())
+		}
+	}
+	return first
+}
+
+// periodProgress adds up the completions up to now by the period they fall in, keyed by the Unix time of
+// the start of the period.
+func periodProgress(target Target, period period, completions Completions, now time.Time) map[int64]float64 {
+	progress := make(map[int64]float64, len(completions))
+	for _, completion := range completions {
+		if !completion.CompletedAt.After(now) {
+			start := period.start(completion.CompletedAt.In(now.Location())).Unix()
+			progress[start] = target.add(progress[start], completion.Amount)
+		}
+	}
+	return progress
+}
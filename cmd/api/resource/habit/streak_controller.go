@@ -0,0 +1,70 @@
+package habit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"time"
+)
+
+// GetStreak godoc
+//
+//	@summary		Get habit streak
+//	@description	Get the current and longest streak and the completion rate of a habit, calculated from its schedule, completions and pauses
+//	@tags			habits
+//	@accept			json
+//	@produce		json
+//	@param			id	path	string	true	"Habit ID"
+//	@success		200	{object}	JsonStreak
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/habits/{id}/streak [get]
+func (a *Api) GetStreak(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	habit, err := a.repository.GetHabit(identity.UserID(r.Context()), id)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	streak, err := a.calculateStreak(habit)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, streak.ToJson())
+}
+
+func (a *Api) calculateStreak(habit *Habit) (Streak, error) {
+	completions, err := a.repository.Completions().GetCompletions(habit.ID, time.Time{}, time.Time{})
+	if err != nil {
+		return Streak{}, err
+	}
+
+	pauses, err := a.repository.Pauses().GetPauses(habit.ID)
+	if err != nil {
+		return Streak{}, err
+	}
+
+	return CalculateStreak(habit, completions, pauses, time.Now().UTC())
+}
+
+func (a *Api) calculateProgress(habit *Habit) (Progress, error) {
+	completions, err := a.repository.Completions().GetCompletions(habit.ID, time.Time{}, time.Time{})
+	if err != nil {
+		return Progress{}, err
+	}
+
+	return CalculateProgress(habit, completions, time.Now().UTC())
+}
@@ -0,0 +1,67 @@
+package habit
+
+// The aggregations of a target, how the amounts of the completions in a period add up to its progress.
+// Count ignores the amounts and counts the completions.
+const (
+	AggregationSum   = "sum"
+	AggregationMax   = "max"
+	AggregationCount = "count"
+)
+
+type JsonTarget struct {
+	Value       float64 `json:"value" validate:"gt=0"`
+	Unit        string  `json:"unit" validate:"max=32"`
+	Aggregation string  `json:"aggregation" validate:"required,oneof=sum max count"`
+}
+
+// Target makes a habit measurable, like drinking 8 glasses a day or running 5 km a week. A period of the
+// schedule is done once the completions in it add up to Value, Unit names what their amounts measure.
+// The zero target is a yes/no habit, which is done by the number of completions the schedule requires.
+type Target struct {
+	Value       float64
+	Unit        string
+	Aggregation string
+}
+
+func (t Target) Measurable() bool {
+	return t.Value > 0
+}
+
+func (t Target) ToJson() JsonTarget {
+	return JsonTarget{
+		Value:       t.Value,
+		Unit:        t.Unit,
+		Aggregation: t.Aggregation,
+	}
+}
+
+func (t JsonTarget) ToTarget() Target {
+	return Target{
+		Value:       t.Value,
+		Unit:        t.Unit,
+		Aggregation: t.Aggregation,
+	}
+}
+
+// goal is the progress which completes a period.
+func (t Target) goal(period period) float64 {
+	if t.Measurable() {
+		return t.Value
+	}
+	return float64(period.required)
+}
+
+// add returns the progress of a period after a completion with the given amount.
+func (t Target) add(progress float64, amount float64) float64 {
+	if !t.Measurable() {
+		return progress + 1
+	}
+
+	switch t.Aggregation {
+	case AggregationSum:
+		return progress + amount
+	case AggregationMax:
+		return max(progress, amount)
+	}
+	return progress + 1
+}
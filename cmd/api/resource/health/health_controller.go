@@ -1,14 +1,149 @@
 package health
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"sync/atomic"
+	"time"
 
-// HealthCheckHandler Read godoc
+	"gorm.io/gorm"
+)
+
+const (
+	checkTimeout = 2 * time.Second
+	// versionQuery reads the version the database is migrated to the way goose does: the newest migration
+	// whose latest record is applied, a migration which was rolled back no longer counts.
+	versionQuery = `SELECT COALESCE((
+		SELECT version_id FROM goose_db_version AS migration
+		WHERE is_applied AND id = (SELECT MAX(id) FROM goose_db_version WHERE version_id = migration.version_id)
+		ORDER BY id DESC LIMIT 1), 0)`
+)
+
+// Api reports whether the server should receive traffic. Once draining, the readiness check fails so that
+// load balancers stop routing requests to the server while it finishes the requests in flight.
+type Api struct {
+	database         *gorm.DB
+	migrationVersion int64
+	draining         atomic.Bool
+}
+
+// New creates the health checks of the database. A zero migrationVersion skips the migration check, for
+// databases which are not managed by goose.
+func New(database *gorm.DB, migrationVersion int64) *Api {
+	return &Api{database: database, migrationVersion: migrationVersion}
+}
+
+// Drain makes every following health and readiness check fail.
+func (a *Api) Drain() {
+	a.draining.Store(true)
+}
+
+// HealthCheck godoc
 //
 //	@summary		Health check
-//	@description	Health check
+//	@description	Health check, fails with 503 while the server is shutting down. Kept for old deployments, use /health/live and /health/ready instead
 //	@tags			health
 //	@success		200
+//	@failure		503
 //	@router			/health [get]
-func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
+func (a *Api) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	if a.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// Live godoc
+//
+//	@summary		Liveness probe
+//	@description	Succeeds as long as the server is able to answer requests, dependencies are not checked
+//	@tags			health
+//	@success		200
+//	@router			/health/live [get]
+func (a *Api) Live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Ready godoc
+//
+//	@summary		Readiness probe
+//	@description	Checks that the database is reachable and migrated to the expected version, fails with 503 when a check fails or the server is shutting down
+//	@tags			health
+//	@produce		json
+//	@success		200	{object}	JsonHealth
+//	@failure		503	{object}	JsonHealth
+//	@router			/health/ready [get]
+func (a *Api) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	health := JsonHealth{Status: StatusOK, Checks: map[string]JsonCheck{}}
+	health.Checks["database"] = a.checkDatabase(ctx)
+	if a.migrationVersion != 0 {
+		health.Checks["migrations"] = a.checkMigrations(ctx)
+	}
+
+	for _, check := range health.Checks {
+		if check.Status != StatusOK {
+			health.Status = StatusFail
+		}
+	}
+	if a.draining.Load() {
+		health.Status = StatusFail
+	}
+
+	status := http.StatusOK
+	if health.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, status, health)
+}
+
+func (a *Api) checkDatabase(ctx context.Context) JsonCheck {
+	start := time.Now()
+
+	sqlDatabase, err := a.database.DB()
+	if err == nil {
+		err = sqlDatabase.PingContext(ctx)
+	}
+	return newCheck(start, err)
+}
+
+func (a *Api) checkMigrations(ctx context.Context) JsonCheck {
+	start := time.Now()
+
+	var version int64
+	err := a.database.WithContext(ctx).
+		Raw(versionQuery).
+		Scan(&version).Error
+	if err != nil {
+		check := newCheck(start, err)
+		check.ExpectedVersion = &a.migrationVersion
+		return check
+	}
+
+	if version != a.migrationVersion {
+		err = fmt.Errorf("database is at migration %d, expected %d", version, a.migrationVersion)
+	}
+	check := newCheck(start, err)
+	check.Version = &version
+	check.ExpectedVersion = &a.migrationVersion
+	return check
+}
+
+func newCheck(start time.Time, err error) JsonCheck {
+	check := JsonCheck{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = StatusFail
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -0,0 +1,21 @@
+package health
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type JsonHealth struct {
+	Status string               `json:"status"`
+	Checks map[string]JsonCheck `json:"checks"`
+}
+
+// JsonCheck is the result of checking a single dependency. Version and ExpectedVersion are only reported
+// by the migrations check.
+type JsonCheck struct {
+	Status          string  `json:"status"`
+	LatencyMs       float64 `json:"latencyMs"`
+	Error           string  `json:"error,omitempty"`
+	Version         *int64  `json:"version,omitempty"`
+	ExpectedVersion *int64  `json:"expectedVersion,omitempty"`
+}
@@ -0,0 +1,138 @@
+package icon
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/common/response"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+type Api struct {
+	store    Store
+	maxBytes int64
+}
+
+func New(store Store, maxBytes int64) *Api {
+	return &Api{
+		store:    store,
+		maxBytes: maxBytes,
+	}
+}
+
+// UploadIcon godoc
+//
+//	@summary		Upload icon
+//	@description	Upload a PNG or SVG icon, either as the raw request body or as the icon field of a multipart form.
+//	@description	Icons are identified by the hash of their content, uploading an existing icon returns 200 instead of 201
+//	@tags			icons
+//	@accept			png,svg,mpfd
+//	@produce		json
+//	@success		200	{object}	JsonIcon
+//	@success		201	{object}	JsonIcon
+//	@failure		400	{object}	error.Problem
+//	@failure		413	{object}	error.Problem
+//	@failure		415	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/icons [post]
+func (a *Api) UploadIcon(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, a.maxBytes)
+
+	data, err := a.readUpload(r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			e.TooLarge(w, r, e.IconTooLarge)
+			return
+		}
+		e.BadRequest(w, r, e.IconReadFailure)
+		return
+	}
+
+	icon, err := FromBytes(data)
+	if err != nil {
+		e.UnsupportedMediaType(w, r, e.UnsupportedIcon)
+		return
+	}
+
+	created, err := a.store.Save(r.Context(), icon)
+	if err != nil {
+		e.ServerError(w, r, e.CreateFailure)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Location", URL(icon.ID))
+	w.Header().Set(headers.CREATED_ID, icon.ID)
+	response.JSON(w, r, status, icon.ToJson())
+}
+
+// GetIcon godoc
+//
+//	@summary		Get icon
+//	@description	Get the content of an icon, icons never change so they can be cached indefinitely
+//	@tags			icons
+//	@produce		png,svg
+//	@param			id	path	string	true	"Icon ID"
+//	@success		200
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/icons/{id} [get]
+func (a *Api) GetIcon(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !ValidID(id) {
+		e.NotFound(w, r, e.IconNotFound)
+		return
+	}
+
+	etag := `"` + id + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	icon, err := a.store.Load(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrIconNotFound) {
+			e.NotFound(w, r, e.IconNotFound)
+			return
+		}
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(icon.Size, 10))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	// SVG icons are served from the API origin, so any script inside them must never run.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := w.Write(icon.Data); err != nil {
+		return
+	}
+}
+
+func (a *Api) readUpload(r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return io.ReadAll(r.Body)
+	}
+
+	file, _, err := r.FormFile("icon")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
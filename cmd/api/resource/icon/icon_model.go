@@ -0,0 +1,124 @@
+package icon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypePNG = "image/png"
+	ContentTypeSVG = "image/svg+xml"
+)
+
+var (
+	ErrIconNotFound       = errors.New("icon not found")
+	ErrUnsupportedContent = errors.New("icon must be a PNG or SVG image")
+	ErrTooLarge           = errors.New("icon is too large")
+
+	idPattern = regexp.MustCompile("^[0-9a-f]{64}$")
+)
+
+type JsonIcon struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// Icon is an uploaded image, identified by the SHA-256 hash of its content so that uploading the same
+// image twice stores it only once.
+type Icon struct {
+	ID          string `gorm:"primary_key"`
+	ContentType string
+	Size        int64
+	Data        []byte
+	CreatedAt   time.Time
+}
+
+// FromBytes validates that data is a PNG or SVG image and returns it as an icon.
+func FromBytes(data []byte) (*Icon, error) {
+	contentType, err := detectContentType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	return &Icon{
+		ID:          hex.EncodeToString(hash[:]),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Data:        data,
+	}, nil
+}
+
+// FromDataURL decodes the legacy inline icons, either a base64 data URL or plain base64.
+func FromDataURL(dataURL string) (*Icon, error) {
+	encoded := dataURL
+	if strings.HasPrefix(dataURL, "data:") {
+		_, encoded, _ = strings.Cut(dataURL, ",")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, ErrUnsupportedContent
+	}
+	return FromBytes(data)
+}
+
+func (i Icon) ToJson() JsonIcon {
+	return JsonIcon{
+		ID:          i.ID,
+		URL:         URL(i.ID),
+		ContentType: i.ContentType,
+		Size:        i.Size,
+	}
+}
+
+func URL(id string) string {
+	return "/v1/icons/" + id
+}
+
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+func detectContentType(data []byte) (string, error) {
+	if http.DetectContentType(data) == ContentTypePNG {
+		return ContentTypePNG, nil
+	}
+	if isSVG(data) {
+		return ContentTypeSVG, nil
+	}
+	return "", ErrUnsupportedContent
+}
+
+// isSVG reports whether data is an XML document with an svg root element.
+func isSVG(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			if element.Name.Local != "svg" {
+				return false
+			}
+			// The whole document has to be well-formed, not just the root element.
+			for {
+				if _, err := decoder.Token(); err != nil {
+					return errors.Is(err, io.EOF)
+				}
+			}
+		}
+	}
+}
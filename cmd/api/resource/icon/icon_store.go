@@ -0,0 +1,186 @@
+package icon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StoreDatabase   = "database"
+	StoreFilesystem = "filesystem"
+)
+
+// Store keeps icon content addressed by the icon ID. Saving an icon which is already stored leaves the
+// stored copy untouched and reports created as false.
+type Store interface {
+	Save(ctx context.Context, icon *Icon) (created bool, err error)
+	Load(ctx context.Context, id string) (*Icon, error)
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+func NewStore(kind string, directory string, database *gorm.DB) (Store, error) {
+	switch kind {
+	case StoreDatabase:
+		return NewDatabaseStore(database), nil
+	case StoreFilesystem:
+		return NewLocalStore(directory, database), nil
+	}
+	return nil, fmt.Errorf("unknown icon store %q", kind)
+}
+
+type DatabaseStore struct {
+	database *gorm.DB
+}
+
+func NewDatabaseStore(database *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{database}
+}
+
+func (store *DatabaseStore) Save(ctx context.Context, icon *Icon) (bool, error) {
+	result := store.database.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(icon)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (store *DatabaseStore) Load(ctx context.Context, id string) (*Icon, error) {
+	icon := &Icon{}
+	if err := store.database.WithContext(ctx).
+		Where("id = ?", id).
+		First(&icon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIconNotFound
+		}
+		return nil, err
+	}
+	return icon, nil
+}
+
+func (store *DatabaseStore) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := store.database.WithContext(ctx).
+		Model(&Icon{}).
+		Where("id = ?", id).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// LocalStore keeps every icon in its own file, spread over sub directories named after the first two
+// characters of the ID. Habits reference icons by a foreign key, so every icon also has a row in the icons
+// table, without its data.
+type LocalStore struct {
+	directory string
+	database  *gorm.DB
+}
+
+func NewLocalStore(directory string, database *gorm.DB) *LocalStore {
+	return &LocalStore{directory, database}
+}
+
+func (store *LocalStore) Save(ctx context.Context, icon *Icon) (bool, error) {
+	if err := store.write(icon); err != nil {
+		return false, err
+	}
+
+	result := store.database.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Icon{ID: icon.ID, ContentType: icon.ContentType, Size: icon.Size, Data: []byte{}})
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// write stores the content of the icon in its file, unless the file already exists.
+func (store *LocalStore) write(icon *Icon) error {
+	path, err := store.path(icon.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Writing to a temporary file first means a crash never leaves a partial icon under its final name.
+	temporary, err := os.CreateTemp(filepath.Dir(path), icon.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.Write(icon.Data); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), path)
+}
+
+func (store *LocalStore) Load(_ context.Context, id string) (*Icon, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrIconNotFound
+		}
+		return nil, err
+	}
+
+	icon, err := FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		icon.CreatedAt = info.ModTime()
+	}
+	return icon, nil
+}
+
+// Exists reports whether the icon can be referenced by a habit, which takes both its file and its row.
+func (store *LocalStore) Exists(ctx context.Context, id string) (bool, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var count int64
+	err = store.database.WithContext(ctx).
+		Model(&Icon{}).
+		Where("id = ?", id).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+func (store *LocalStore) path(id string) (string, error) {
+	if !ValidID(id) {
+		return "", ErrIconNotFound
+	}
+	return filepath.Join(store.directory, id[:2], id), nil
+}
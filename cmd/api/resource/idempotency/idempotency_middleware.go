@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	maxBodyBytes = 1 << 20
+)
+
+// Keys makes POST requests with an Idempotency-Key header safe to retry: the first request with a key is
+// handled and its response stored, later requests of the same user with the key get that response replayed
+// for the configured window instead of being handled again.
+type Keys struct {
+	repository *Repository
+	window     time.Duration
+}
+
+func New(database *gorm.DB, window time.Duration) *Keys {
+	return &Keys{repository: NewRepository(database), window: window}
+}
+
+// Middleware must run after the auth middleware, keys are scoped to the authenticated user. A key reused
+// with a different request is rejected, as is a key whose first request is still being handled. Server
+// errors are not stored, so that the request can be retried with the same key.
+func (k *Keys) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			e.BadRequest(w, r, e.InvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				e.TooLarge(w, r, e.RequestTooLarge)
+				return
+			}
+			e.BadRequest(w, r, e.RequestReadFailure)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &Record{
+			UserID:      identity.UserID(r.Context()),
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+			ExpiresAt:   time.Now().Add(k.window),
+		}
+
+		reserved, err := k.repository.Reserve(r.Context(), record)
+		if err != nil {
+			e.ServerError(w, r, e.DatabaseConnectionFailed)
+			return
+		}
+		if !reserved {
+			k.replay(w, r, record)
+			return
+		}
+
+		k.record(w, r, next, record)
+	})
+}
+
+// record handles the request while keeping a copy of the response, which is stored for the key.
+func (k *Keys) record(w http.ResponseWriter, r *http.Request, next http.Handler, record *Record) {
+	stored := false
+	defer func() {
+		if !stored {
+			// The request panicked or failed on the server, the client should be able to retry it.
+			if err := k.repository.Release(context.WithoutCancel(r.Context()), record.UserID, record.Key); err != nil {
+				log.Printf("Idempotency key release failure: %s", err)
+			}
+		}
+	}()
+
+	response := &bytes.Buffer{}
+	writer := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	writer.Tee(response)
+
+	next.ServeHTTP(writer, r)
+
+	record.Status = writer.Status()
+	if record.Status == 0 {
+		record.Status = http.StatusOK
+	}
+	if record.Status >= http.StatusInternalServerError {
+		return
+	}
+
+	record.Location = w.Header().Get("Location")
+	record.CreatedID = w.Header().Get(headers.CREATED_ID)
+	record.ContentType = w.Header().Get("Content-Type")
+	record.ETag = w.Header().Get("ETag")
+	record.Body = response.Bytes()
+
+	if err := k.repository.Complete(context.WithoutCancel(r.Context()), record); err != nil {
+		log.Printf("Idempotency key store failure: %s", err)
+		return
+	}
+	stored = true
+}
+
+// replay answers a request whose key is already in use with the stored response.
+func (k *Keys) replay(w http.ResponseWriter, r *http.Request, record *Record) {
+	stored, err := k.repository.Get(r.Context(), record.UserID, record.Key)
+	switch {
+	case errors.Is(err, ErrRecordNotFound):
+		// The first request failed and released the key in the meantime.
+		e.Conflict(w, r, e.IdempotencyKeyInProgress)
+		return
+	case err != nil:
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	case stored.Fingerprint != record.Fingerprint:
+		e.Unprocessable(w, r, e.IdempotencyKeyReused)
+		return
+	case !stored.completed():
+		e.Conflict(w, r, e.IdempotencyKeyInProgress)
+		return
+	}
+
+	for name, value := range map[string]string{
+		"Location":         stored.Location,
+		headers.CREATED_ID: stored.CreatedID,
+		"Content-Type":     stored.ContentType,
+		"ETag":             stored.ETag,
+	} {
+		if value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	if _, err := w.Write(stored.Body); err != nil {
+		log.Printf("Error writing response: %s", err)
+	}
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,31 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record is an idempotency key used by a user, with the fingerprint of the request it was first sent with
+// and the response to replay for it. Status is 0 while that request is still being handled.
+type Record struct {
+	UserID      uuid.UUID `gorm:"primary_key"`
+	Key         string    `gorm:"primary_key"`
+	Fingerprint string
+	Status      int
+	Location    string
+	CreatedID   string
+	ContentType string
+	ETag        string `gorm:"column:etag"`
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+func (r Record) completed() bool {
+	return r.Status != 0
+}
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRecordNotFound = errors.New("idempotency key not found")
+
+type Repository struct {
+	database *gorm.DB
+}
+
+func NewRepository(database *gorm.DB) *Repository {
+	return &Repository{database}
+}
+
+// Reserve stores the record unless its key is already in use by the user, an expired use of the key is
+// replaced. It reports whether the record was stored.
+func (repository *Repository) Reserve(ctx context.Context, record *Record) (bool, error) {
+	if err := repository.database.WithContext(ctx).
+		Where("user_id = ? AND key = ? AND expires_at <= ?", record.UserID, record.Key, time.Now()).
+		Delete(&Record{}).Error; err != nil {
+		return false, err
+	}
+
+	result := repository.database.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(record)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (repository *Repository) Get(ctx context.Context, userID uuid.UUID, key string) (*Record, error) {
+	record := &Record{}
+	if err := repository.database.WithContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// Complete stores the response of a reserved record.
+func (repository *Repository) Complete(ctx context.Context, record *Record) error {
+	return repository.database.WithContext(ctx).
+		Model(&Record{}).
+		Select("Status", "Location", "CreatedID", "ContentType", "ETag", "Body").
+		Where("user_id = ? AND key = ?", record.UserID, record.Key).
+		Updates(record).Error
+}
+
+// Release deletes a reserved record, so that the key can be retried.
+func (repository *Repository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return repository.database.WithContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		Delete(&Record{}).Error
+}
+
+func (repository *Repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := repository.database.WithContext(ctx).Where("expires_at <= ?", now).Delete(&Record{})
+
+	return result.RowsAffected, result.Error
+}
+
+// Sweep deletes the expired records every interval until ctx is done.
+func (repository *Repository) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := repository.DeleteExpired(ctx, now); err != nil {
+				log.Printf("Idempotency key sweep failure: %s", err)
+			}
+		}
+	}
+}
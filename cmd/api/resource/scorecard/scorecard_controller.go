@@ -0,0 +1,327 @@
+package scorecard
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"habitgobackend/cmd/api/resource/habit"
+	"net/http"
+	"time"
+)
+
+// knownErrors are the errors caused by the request rather than the server, with the status they are
+// reported with.
+var knownErrors = []e.Known{
+	{Err: ErrEntryNotFound, Status: http.StatusNotFound, Problem: e.EntryNotFound},
+	{Err: ErrInvalidOrder, Status: http.StatusUnprocessableEntity, Problem: e.InvalidOrder},
+	{Err: habit.ErrHabitNotFound, Status: http.StatusUnprocessableEntity, Problem: e.UnknownHabit},
+}
+
+type Api struct {
+	repository *Repository
+	habits     habit.HabitStore
+	validator  *validator.Validate
+}
+
+func New(db *gorm.DB, habits habit.HabitStore, validator *validator.Validate) *Api {
+	return &Api{
+		repository: NewRepository(db),
+		habits:     habits,
+		validator:  validator,
+	}
+}
+
+// GetScorecard godoc
+//
+//	@summary		Get scorecard
+//	@description	List the behaviours of the user's day in order, rated positive, negative or neutral, with their tally
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@success		200	{object}	JsonScorecard
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard [get]
+func (a *Api) GetScorecard(w http.ResponseWriter, r *http.Request) {
+	entries, err := a.repository.GetEntries(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, JsonScorecard{Entries: entries.ToJson(), Tally: CountRatings(entries).ToJson()})
+}
+
+// CreateEntry godoc
+//
+//	@summary		Add scorecard entry
+//	@description	Add a behaviour at the end of the scorecard, optionally linked to the habit tracking it
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@param			Idempotency-Key	header	string		false	"Replays the response of an earlier request with the same key"
+//	@param			body			body	JsonEntry	true	"JsonEntry"
+//	@success		201
+//	@failure		400	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard/entries [post]
+func (a *Api) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	jsonEntry := &JsonEntry{}
+	if !response.Decode(w, r, jsonEntry) {
+		return
+	}
+
+	if err := a.validator.Struct(jsonEntry); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	newEntry := jsonEntry.ToEntry()
+	newEntry.ID = uuid.New()
+	newEntry.UserID = identity.UserID(r.Context())
+	newEntry.CreatedAt = time.Now()
+
+	if !a.checkHabit(w, r, newEntry, nil) {
+		return
+	}
+
+	if _, err := a.repository.CreateEntry(newEntry); err != nil {
+		e.ServerError(w, r, e.CreateFailure)
+		return
+	}
+
+	response.Created(w, "/scorecard/entries/"+newEntry.ID.String(), newEntry.ID.String())
+}
+
+// UpdateEntry godoc
+//
+//	@summary		Update scorecard entry
+//	@description	Replace the behaviour, rating and linked habit of a scorecard entry, its position is changed by ordering the scorecard
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@param			id		path	string		true	"Entry ID"
+//	@param			body	body	JsonEntry	true	"JsonEntry"
+//	@success		200	{object}	JsonEntry
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard/entries/{id} [put]
+func (a *Api) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	jsonEntry := &JsonEntry{}
+	if !response.Decode(w, r, jsonEntry) {
+		return
+	}
+
+	if err := a.validator.Struct(jsonEntry); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	userID := identity.UserID(r.Context())
+	current, err := a.repository.GetEntry(userID, id)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+
+	entry := jsonEntry.ToEntry()
+	entry.ID = id
+	entry.UserID = userID
+
+	if !a.checkHabit(w, r, entry, current.HabitID) {
+		return
+	}
+
+	rows, err := a.repository.UpdateEntry(entry)
+	if err != nil {
+		e.ServerError(w, r, e.UpdateFailure)
+		return
+	}
+	if rows == 0 {
+		e.NotFound(w, r, e.EntryNotFound)
+		return
+	}
+
+	updated, err := a.repository.GetEntry(userID, id)
+	if err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return
+	}
+	response.JSON(w, r, http.StatusOK, updated.ToJson())
+}
+
+// DeleteEntry godoc
+//
+//	@summary		Remove scorecard entry
+//	@description	Remove a behaviour from the scorecard, the summary still counts it for the time before it was removed
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@param			id	path	string	true	"Entry ID"
+//	@success		200
+//	@failure		400	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard/entries/{id} [delete]
+func (a *Api) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidUrlRequest)
+		return
+	}
+
+	rows, err := a.repository.DeleteEntry(identity.UserID(r.Context()), id)
+	if err != nil {
+		e.ServerError(w, r, e.DeleteFailure)
+		return
+	}
+	if rows == 0 {
+		e.NotFound(w, r, e.EntryNotFound)
+	}
+}
+
+// OrderScorecard godoc
+//
+//	@summary		Order scorecard
+//	@description	Put the entries of the scorecard in the order of the user's day, the order lists the ID of every entry once
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@param			body	body	JsonOrder	true	"JsonOrder"
+//	@success		200	{object}	JsonScorecard
+//	@failure		400	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard/order [put]
+func (a *Api) OrderScorecard(w http.ResponseWriter, r *http.Request) {
+	order := &JsonOrder{}
+	if !response.Decode(w, r, order) {
+		return
+	}
+
+	if err := a.validator.Struct(order); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	// The IDs passed validation, they are UUIDs.
+	ids := make([]uuid.UUID, len(order.EntryIDs))
+	for i, id := range order.EntryIDs {
+		ids[i] = uuid.MustParse(id)
+	}
+
+	userID := identity.UserID(r.Context())
+	if err := a.repository.OrderEntries(userID, ids); err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+
+	a.GetScorecard(w, r)
+}
+
+// GetSummary godoc
+//
+//	@summary		Get scorecard summary
+//	@description	Tally the scorecard at the end of every day, week or month of a date range to show how the balance of positive and negative behaviours changed
+//	@tags			scorecard
+//	@accept			json
+//	@produce		json
+//	@param			interval	query	string	false	"day, week or month, week by default"
+//	@param			from		query	string	false	"A day of the first period (YYYY-MM-DD), 12 periods before to by default"
+//	@param			to			query	string	false	"A day of the last period (YYYY-MM-DD), today by default"
+//	@success		200	{object}	JsonSummary
+//	@failure		400	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/scorecard/summary [get]
+func (a *Api) GetSummary(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	from, to, interval, err := parseSummaryQuery(r, now)
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidQueryParams)
+		return
+	}
+
+	entries, changes, err := a.repository.GetHistory(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	periods, err := Summarise(entries, changes, from, to, interval, now)
+	if err != nil {
+		e.BadRequest(w, r, e.InvalidQueryParams)
+		return
+	}
+
+	summary := JsonSummary{Interval: interval, Current: CountRatings(entries).ToJson(),
+		Periods: make([]JsonPeriod, 0, len(periods))}
+	for _, period := range periods {
+		summary.Periods = append(summary.Periods, period.ToJson())
+	}
+	response.JSON(w, r, http.StatusOK, summary)
+}
+
+// checkHabit makes sure a changed habit link of the entry names a habit of the user, habits linked before
+// stay linked when they are moved to the trash. It writes the error response and returns false when the
+// habit cannot be linked.
+func (a *Api) checkHabit(w http.ResponseWriter, r *http.Request, entry *Entry, previous *uuid.UUID) bool {
+	if entry.HabitID == nil || (previous != nil && *previous == *entry.HabitID) {
+		return true
+	}
+	if _, err := a.habits.GetHabit(entry.UserID, *entry.HabitID); err != nil {
+		e.FromError(w, r, err, e.DatabaseConnectionFailed, knownErrors...)
+		return false
+	}
+	return true
+}
+
+// parseSummaryQuery reads the interval and the optional from and to days of a summary and returns the
+// days as a half-open range, so that the period of the "to" day is included.
+func parseSummaryQuery(r *http.Request, now time.Time) (time.Time, time.Time, string, error) {
+	query := r.URL.Query()
+
+	interval := IntervalWeek
+	if value := query.Get("interval"); value != "" {
+		interval = value
+	}
+	if interval != IntervalDay && interval != IntervalWeek && interval != IntervalMonth {
+		return time.Time{}, time.Time{}, "", errors.New("unknown interval")
+	}
+
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := query.Get("to"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+		last = parsed
+	}
+	to := nextPeriod(startOfPeriod(last, interval), interval)
+
+	from := previousPeriods(last, interval, defaultPeriods-1)
+	if value := query.Get("from"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, "", errors.New("from must not be after to")
+	}
+	return from, to, interval, nil
+}
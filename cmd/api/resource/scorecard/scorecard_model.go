@@ -0,0 +1,110 @@
+package scorecard
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The ratings of a behaviour on the scorecard: positive behaviours are good habits, negative ones bad
+// habits and neutral ones neither.
+const (
+	RatingPositive = "positive"
+	RatingNegative = "negative"
+	RatingNeutral  = "neutral"
+)
+
+// JsonEntry is a behaviour of the user's day on the scorecard. Position is read only, it is the place of
+// the entry in the day starting at 1 and is changed by ordering the scorecard. HabitID links the entry to
+// the habit tracking the behaviour.
+type JsonEntry struct {
+	ID        string    `json:"id"`
+	Position  int       `json:"position"`
+	Behaviour string    `json:"behaviour" validate:"required,max=200"`
+	Rating    string    `json:"rating" validate:"required,oneof=positive negative neutral"`
+	HabitID   string    `json:"habitId,omitempty" validate:"omitempty,uuid"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// JsonScorecard lists the entries of the scorecard in the order of the day, with their current tally.
+type JsonScorecard struct {
+	Entries []JsonEntry `json:"entries"`
+	Tally   JsonTally   `json:"tally"`
+}
+
+// JsonOrder lists the IDs of every entry of the scorecard in their new order.
+type JsonOrder struct {
+	EntryIDs []string `json:"entryIds" validate:"required,dive,uuid"`
+}
+
+type Entry struct {
+	ID        uuid.UUID `gorm:"primary_key"`
+	UserID    uuid.UUID `gorm:"index"`
+	Position  int       `gorm:"not null"`
+	Behaviour string
+	Rating    string     `gorm:"not null"`
+	HabitID   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// DeletedAt keeps removed entries for the summary, which still counts them before they were removed.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type Entries []*Entry
+
+func (Entry) TableName() string {
+	return "scorecard_entries"
+}
+
+// RatingChange records the rating an entry was given at a time, starting with the rating it was created
+// with, so that the summary knows how the entry was rated in the past.
+type RatingChange struct {
+	ID      uuid.UUID `gorm:"primary_key"`
+	EntryID uuid.UUID `gorm:"index"`
+	Rating  string
+	RatedAt time.Time
+}
+
+type RatingChanges []*RatingChange
+
+func (RatingChange) TableName() string {
+	return "scorecard_rating_changes"
+}
+
+func (e Entry) ToJson() JsonEntry {
+	jsonEntry := JsonEntry{
+		ID:        e.ID.String(),
+		Position:  e.Position,
+		Behaviour: e.Behaviour,
+		Rating:    e.Rating,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+	if e.HabitID != nil {
+		jsonEntry.HabitID = e.HabitID.String()
+	}
+	return jsonEntry
+}
+
+func (e JsonEntry) ToEntry() *Entry {
+	var habitID *uuid.UUID
+	if id, err := uuid.Parse(e.HabitID); err == nil {
+		habitID = &id
+	}
+
+	return &Entry{
+		Behaviour: e.Behaviour,
+		Rating:    e.Rating,
+		HabitID:   habitID,
+	}
+}
+
+func (entries Entries) ToJson() []JsonEntry {
+	jsonEntries := make([]JsonEntry, 0, len(entries))
+	for _, entry := range entries {
+		jsonEntries = append(jsonEntries, entry.ToJson())
+	}
+	return jsonEntries
+}
@@ -0,0 +1,198 @@
+package scorecard
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEntryNotFound = errors.New("scorecard entry not found")
+	ErrInvalidOrder  = errors.New("order does not list every entry of the scorecard once")
+)
+
+// Repository keeps the scorecards of every user. All lookups are scoped to the owning user and leave
+// removed entries out, except for GetHistory.
+type Repository struct {
+	database *gorm.DB
+}
+
+func NewRepository(database *gorm.DB) *Repository {
+	return &Repository{database}
+}
+
+// GetEntries returns the entries of the user's scorecard in the order of the day.
+func (repository *Repository) GetEntries(userID uuid.UUID) (Entries, error) {
+	entries := make([]*Entry, 0)
+	if err := repository.database.
+		Where("user_id = ?", userID).
+		Order("position, id").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (repository *Repository) GetEntry(userID uuid.UUID, id uuid.UUID) (*Entry, error) {
+	entry := &Entry{}
+	if err := repository.database.
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// CreateEntry adds the entry at the end of the scorecard and records its first rating.
+func (repository *Repository) CreateEntry(entry *Entry) (*Entry, error) {
+	err := repository.database.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.
+			Model(&Entry{}).
+			Where("user_id = ?", entry.UserID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		entry.Position = last + 1
+
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		return tx.Create(&RatingChange{ID: uuid.New(), EntryID: entry.ID, Rating: entry.Rating,
+			RatedAt: entry.CreatedAt}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateEntry replaces the behaviour, rating and habit of the entry, recording the rating when it changed.
+func (repository *Repository) UpdateEntry(entry *Entry) (int64, error) {
+	var rows int64
+	err := repository.database.Transaction(func(tx *gorm.DB) error {
+		current := &Entry{}
+		if err := tx.
+			Where("id = ? AND user_id = ?", entry.ID, entry.UserID).
+			First(current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		result := tx.
+			Model(&Entry{}).
+			Select("Behaviour", "Rating", "HabitID").
+			Where("id = ? AND user_id = ?", entry.ID, entry.UserID).
+			Updates(entry)
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+
+		if current.Rating == entry.Rating {
+			return nil
+		}
+		return tx.Create(&RatingChange{ID: uuid.New(), EntryID: entry.ID, Rating: entry.Rating,
+			RatedAt: time.Now()}).Error
+	})
+	return rows, err
+}
+
+// DeleteEntry removes the entry from the scorecard and moves the entries after it up.
+func (repository *Repository) DeleteEntry(userID uuid.UUID, id uuid.UUID) (int64, error) {
+	var rows int64
+	err := repository.database.Transaction(func(tx *gorm.DB) error {
+		entry := &Entry{}
+		if err := tx.
+			Where("id = ? AND user_id = ?", id, userID).
+			First(entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&Entry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
+
+		return tx.
+			Model(&Entry{}).
+			Where("user_id = ? AND position > ?", userID, entry.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+	})
+	return rows, err
+}
+
+// OrderEntries gives the entries of the scorecard the positions of their IDs in ids, which must list every
+// entry of the scorecard once. It returns ErrInvalidOrder otherwise.
+func (repository *Repository) OrderEntries(userID uuid.UUID, ids []uuid.UUID) error {
+	return repository.database.Transaction(func(tx *gorm.DB) error {
+		var current []uuid.UUID
+		if err := tx.
+			Model(&Entry{}).
+			Where("user_id = ?", userID).
+			Pluck("id", &current).Error; err != nil {
+			return err
+		}
+
+		listed := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			listed[id] = true
+		}
+		if len(ids) != len(current) || len(listed) != len(ids) {
+			return ErrInvalidOrder
+		}
+		for _, id := range current {
+			if !listed[id] {
+				return ErrInvalidOrder
+			}
+		}
+
+		for i, id := range ids {
+			if err := tx.
+				Model(&Entry{}).
+				Where("id = ? AND user_id = ?", id, userID).
+				UpdateColumn("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetHistory returns every entry the user's scorecard ever had, removed entries included, together with
+// the rating changes of the entries ordered by when they were rated.
+func (repository *Repository) GetHistory(userID uuid.UUID) (Entries, RatingChanges, error) {
+	entries := make([]*Entry, 0)
+	if err := repository.database.
+		Unscoped().
+		Where("user_id = ?", userID).
+		Order("created_at, id").
+		Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+
+	changes := make([]*RatingChange, 0)
+	if err := repository.database.
+		Where("entry_id IN (?)", repository.database.
+			Unscoped().
+			Model(&Entry{}).
+			Select("id").
+			Where("user_id = ?", userID)).
+		Order("rated_at, id").
+		Find(&changes).Error; err != nil {
+		return nil, nil, err
+	}
+	return entries, changes, nil
+}
@@ -0,0 +1,182 @@
+package scorecard
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The intervals a summary splits its range into. Weeks start on Monday.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// defaultPeriods is how many periods a summary without a from date covers, the current one included.
+	defaultPeriods = 12
+	maxPeriods     = 366
+)
+
+var ErrTooManyPeriods = errors.New("summary has too many periods")
+
+type JsonTally struct {
+	Positive int `json:"positive"`
+	Negative int `json:"negative"`
+	Neutral  int `json:"neutral"`
+	// Balance is the number of positive minus the number of negative behaviours.
+	Balance int `json:"balance"`
+}
+
+// JsonPeriod is the tally of the scorecard at the end of a period, or now for the current period. End is
+// the last day of the period.
+type JsonPeriod struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	JsonTally
+}
+
+// JsonSummary shows how the balance of good and bad behaviours on the scorecard changed over time.
+type JsonSummary struct {
+	Interval string       `json:"interval"`
+	Current  JsonTally    `json:"current"`
+	Periods  []JsonPeriod `json:"periods"`
+}
+
+// Tally counts the behaviours on the scorecard by rating.
+type Tally struct {
+	Positive int
+	Negative int
+	Neutral  int
+}
+
+func (t Tally) Balance() int {
+	return t.Positive - t.Negative
+}
+
+func (t Tally) ToJson() JsonTally {
+	return JsonTally{Positive: t.Positive, Negative: t.Negative, Neutral: t.Neutral, Balance: t.Balance()}
+}
+
+// Period is a period of a summary, from Start up to the exclusive End.
+type Period struct {
+	Start time.Time
+	End   time.Time
+	Tally Tally
+}
+
+func (p Period) ToJson() JsonPeriod {
+	return JsonPeriod{
+		Start:     p.Start.Format(dateLayout),
+		End:       p.End.AddDate(0, 0, -1).Format(dateLayout),
+		JsonTally: p.Tally.ToJson(),
+	}
+}
+
+// CountRatings tallies the entries by their current rating, leaving removed entries out.
+func CountRatings(entries Entries) Tally {
+	tally := Tally{}
+	for _, entry := range entries {
+		if !entry.DeletedAt.Valid {
+			tally.add(entry.Rating)
+		}
+	}
+	return tally
+}
+
+// Summarise tallies the scorecard at the end of every period of the interval between from and the
+// exclusive to, the first period being the one from falls in. The scorecard at a time is made of the
+// entries created before it and not yet removed, with the rating they had then. The current period is
+// tallied at now, periods starting after now are left out.
+func Summarise(entries Entries, changes RatingChanges, from, to time.Time, interval string, now time.Time) ([]Period, error) {
+	ratings := make(map[uuid.UUID]RatingChanges, len(entries))
+	for _, change := range changes {
+		ratings[change.EntryID] = append(ratings[change.EntryID], change)
+	}
+
+	periods := make([]Period, 0)
+	for start := startOfPeriod(from, interval); start.Before(to) && !start.After(now); start = nextPeriod(start, interval) {
+		if len(periods) == maxPeriods {
+			return nil, ErrTooManyPeriods
+		}
+
+		end := nextPeriod(start, interval)
+		at := end
+		if at.After(now) {
+			at = now
+		}
+		periods = append(periods, Period{Start: start, End: end, Tally: tallyAt(entries, ratings, at)})
+	}
+	return periods, nil
+}
+
+// tallyAt tallies the entries on the scorecard at the given time.
+func tallyAt(entries Entries, ratings map[uuid.UUID]RatingChanges, at time.Time) Tally {
+	tally := Tally{}
+	for _, entry := range entries {
+		if !entry.CreatedAt.Before(at) || (entry.DeletedAt.Valid && !entry.DeletedAt.Time.After(at)) {
+			continue
+		}
+
+		// Entries are rated when they are created, so the current rating is only left when the entry has
+		// no recorded ratings.
+		rating := entry.Rating
+		for _, change := range ratings[entry.ID] {
+			if change.RatedAt.After(at) {
+				break
+			}
+			rating = change.Rating
+		}
+		tally.add(rating)
+	}
+	return tally
+}
+
+func (t *Tally) add(rating string) {
+	switch rating {
+	case RatingPositive:
+		t.Positive++
+	case RatingNegative:
+		t.Negative++
+	case RatingNeutral:
+		t.Neutral++
+	}
+}
+
+// startOfPeriod returns the first day of the period of the interval which the day of t falls in.
+func startOfPeriod(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case IntervalWeek:
+		// Weekday counts from Sunday, weeks start on Monday.
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// previousPeriods returns the start of the period the given number of periods before the one t falls in.
+func previousPeriods(t time.Time, interval string, periods int) time.Time {
+	start := startOfPeriod(t, interval)
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, -7*periods)
+	case IntervalMonth:
+		return start.AddDate(0, -periods, 0)
+	}
+	return start.AddDate(0, 0, -periods)
+}
@@ -0,0 +1,221 @@
+package user
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/cmd/api/resource/common/response"
+	"net/http"
+	"strings"
+)
+
+import "gorm.io/gorm"
+
+// knownErrors are the errors caused by the request rather than the server.
+var knownErrors = []e.Known{
+	{Err: ErrEmailTaken, Status: http.StatusConflict, Problem: e.EmailTaken},
+}
+
+type Api struct {
+	repository *Repository
+	validator  *validator.Validate
+}
+
+func New(db *gorm.DB, validator *validator.Validate) *Api {
+	return &Api{
+		repository: NewRepository(db),
+		validator:  validator,
+	}
+}
+
+// CreateUser godoc
+//
+//	@summary		Register user
+//	@description	Register a new user account
+//	@tags			users
+//	@accept			json
+//	@produce		json
+//	@param			body	body	JsonRegistration	true	"JsonRegistration"
+//	@success		201
+//	@failure		400	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/users [post]
+func (a *Api) CreateUser(w http.ResponseWriter, r *http.Request) {
+	registration := &JsonRegistration{}
+	if !response.Decode(w, r, registration) {
+		return
+	}
+
+	registration.Email = NormaliseEmail(registration.Email)
+	if err := a.validator.Struct(registration); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	if taken, err := a.emailTaken(registration.Email, uuid.Nil); err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	} else if taken {
+		e.Conflict(w, r, e.EmailTaken)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(registration.Password), bcrypt.DefaultCost)
+	if err != nil {
+		e.ServerError(w, r, e.CreateFailure)
+		return
+	}
+
+	newUser := &User{
+		ID:           uuid.New(),
+		Email:        registration.Email,
+		Name:         registration.Name,
+		PasswordHash: string(passwordHash),
+	}
+
+	if _, err := a.repository.CreateUser(newUser); err != nil {
+		e.FromError(w, r, err, e.CreateFailure, knownErrors...)
+		return
+	}
+
+	response.Created(w, "/users/me", newUser.ID.String())
+}
+
+// GetUser godoc
+//
+//	@summary		Get profile
+//	@description	Get the profile of the calling user
+//	@tags			users
+//	@accept			json
+//	@produce		json
+//	@success		200	{object}	JsonUser
+//	@failure		401	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/users/me [get]
+func (a *Api) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := a.repository.GetUser(identity.UserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			e.NotFound(w, r, e.UserNotFound)
+			return
+		}
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, user.ToJson())
+}
+
+// UpdateUser godoc
+//
+//	@summary		Update profile
+//	@description	Update the profile of the calling user, the password is only changed when sent
+//	@tags			users
+//	@accept			json
+//	@produce		json
+//	@param			body	body	JsonProfile	true	"JsonProfile"
+//	@success		200
+//	@failure		400	{object}	error.Problem
+//	@failure		401	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		409	{object}	error.Problem
+//	@failure		422	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/users/me [put]
+func (a *Api) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	profile := &JsonProfile{}
+	if !response.Decode(w, r, profile) {
+		return
+	}
+
+	profile.Email = NormaliseEmail(profile.Email)
+	if err := a.validator.Struct(profile); err != nil {
+		e.ValidationErrors(w, r, err)
+		return
+	}
+
+	user, err := a.repository.GetUser(identity.UserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			e.NotFound(w, r, e.UserNotFound)
+			return
+		}
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	}
+
+	if taken, err := a.emailTaken(profile.Email, user.ID); err != nil {
+		e.ServerError(w, r, e.DatabaseConnectionFailed)
+		return
+	} else if taken {
+		e.Conflict(w, r, e.EmailTaken)
+		return
+	}
+
+	user.Email = profile.Email
+	user.Name = profile.Name
+	if profile.Password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(profile.Password), bcrypt.DefaultCost)
+		if err != nil {
+			e.ServerError(w, r, e.UpdateFailure)
+			return
+		}
+		user.PasswordHash = string(passwordHash)
+	}
+
+	rows, err := a.repository.UpdateUser(user)
+	if err != nil {
+		e.FromError(w, r, err, e.UpdateFailure, knownErrors...)
+		return
+	}
+
+	if rows == 0 {
+		e.NotFound(w, r, e.UserNotFound)
+	}
+}
+
+// DeleteUser godoc
+//
+//	@summary		Delete account
+//	@description	Delete the calling user together with all of their habits
+//	@tags			users
+//	@accept			json
+//	@produce		json
+//	@success		200
+//	@failure		401	{object}	error.Problem
+//	@failure		404	{object}	error.Problem
+//	@failure		500	{object}	error.Problem
+//	@router			/users/me [delete]
+func (a *Api) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.repository.DeleteUser(identity.UserID(r.Context()))
+	if err != nil {
+		e.ServerError(w, r, e.DeleteFailure)
+		return
+	}
+	if rows == 0 {
+		e.NotFound(w, r, e.UserNotFound)
+	}
+}
+
+// emailTaken reports whether the email belongs to a user other than the given one.
+func (a *Api) emailTaken(email string, userID uuid.UUID) (bool, error) {
+	existing, err := a.repository.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return existing.ID != userID, nil
+}
+
+// NormaliseEmail is applied to every email before it is stored or looked up, emails are case-insensitive.
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JsonUser struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email" validate:"required,email,max=254"`
+	Name      string    `json:"name" validate:"required,max=100"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type JsonRegistration struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type JsonProfile struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+	Name  string `json:"name" validate:"required,max=100"`
+	// Password is only changed when it is sent.
+	Password string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
+}
+
+type User struct {
+	ID           uuid.UUID `gorm:"primary_key"`
+	Email        string
+	Name         string
+	PasswordHash string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (u User) ToJson() JsonUser {
+	return JsonUser{
+		ID:        u.ID.String(),
+		Email:     u.Email,
+		Name:      u.Name,
+		CreatedAt: u.CreatedAt,
+	}
+}
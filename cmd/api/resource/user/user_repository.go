@@ -0,0 +1,89 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrEmailTaken is returned when the email already belongs to another user, which the unique email
+// constraint catches even when two requests race for the same email.
+var ErrEmailTaken = errors.New("email is already taken")
+
+type Repository struct {
+	database *gorm.DB
+}
+
+func NewRepository(database *gorm.DB) *Repository {
+	return &Repository{database}
+}
+
+// CreateUser stores a new user. The first user also becomes the owner of the habits created before the API
+// had users, which belonged to whoever ran it.
+func (repository *Repository) CreateUser(user *User) (*User, error) {
+	err := repository.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return repository.translate(err)
+		}
+
+		var users int64
+		if err := tx.Model(&User{}).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 1 {
+			return nil
+		}
+		return tx.Exec("UPDATE habits SET user_id = ? WHERE user_id IS NULL", user.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repository *Repository) GetUser(id uuid.UUID) (*User, error) {
+	user := &User{}
+	if err := repository.database.
+		Where("id = ?", id).
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repository *Repository) GetUserByEmail(email string) (*User, error) {
+	user := &User{}
+	if err := repository.database.
+		Where("email = ?", email).
+		First(&user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repository *Repository) UpdateUser(user *User) (int64, error) {
+	result := repository.database.
+		Model(&User{}).
+		Select("Email", "Name", "PasswordHash").
+		Where("id = ?", user.ID).
+		Updates(user)
+
+	return result.RowsAffected, repository.translate(result.Error)
+}
+
+func (repository *Repository) DeleteUser(id uuid.UUID) (int64, error) {
+	result := repository.database.Where("id = ?", id).Delete(&User{})
+
+	return result.RowsAffected, result.Error
+}
+
+// translate turns the unique violation of the email into ErrEmailTaken.
+func (repository *Repository) translate(err error) error {
+	if translator, ok := repository.database.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		if errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+			return ErrEmailTaken
+		}
+	}
+	return err
+}
@@ -8,8 +8,11 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Icons       IconConfig
+	Idempotency IdempotencyConfig
+	Trash       TrashConfig
 }
 type ServerConfig struct {
 	Port         int           `env:"SERVER_PORT,required"`
@@ -17,15 +20,54 @@ type ServerConfig struct {
 	TimeoutWrite time.Duration `env:"SERVER_TIMEOUT_WRITE,required"`
 	TimeoutIdle  time.Duration `env:"SERVER_TIMEOUT_IDLE,required"`
 	Debug        bool          `env:"SERVER_DEBUG,required"`
+	Auth         AuthConfig
+	Shutdown     ShutdownConfig
 }
 
+// ShutdownConfig controls how the server stops. The health check fails for Delay before the server stops
+// accepting connections, then in-flight requests get up to Timeout to finish.
+type ShutdownConfig struct {
+	Delay   time.Duration `env:"SERVER_SHUTDOWN_DELAY,default=5s"`
+	Timeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT,default=20s"`
+}
+
+type AuthConfig struct {
+	JwtSecret       string        `env:"SERVER_JWT_SECRET,required"`
+	AccessTokenTTL  time.Duration `env:"SERVER_ACCESS_TOKEN_TTL,default=15m"`
+	RefreshTokenTTL time.Duration `env:"SERVER_REFRESH_TOKEN_TTL,default=720h"`
+}
+
+// DatabaseConfig selects the database with Driver, one of postgres, sqlite or memory, memory being an
+// in-memory SQLite database. The connection settings are only used by postgres and Path only by sqlite.
 type DatabaseConfig struct {
-	Host         string `env:"DB_HOST,required"`
-	Port         int    `env:"DB_PORT,required"`
-	Username     string `env:"DB_USER,required"`
-	Password     string `env:"DB_PASS,required"`
-	DatabaseName string `env:"DB_NAME,required"`
-	Debug        bool   `env:"DB_DEBUG,required"`
+	Driver       string `env:"DB_DRIVER,default=postgres"`
+	Path         string `env:"DB_PATH,default=habits.db"`
+	Host         string `env:"DB_HOST"`
+	Port         int    `env:"DB_PORT,default=5432"`
+	Username     string `env:"DB_USER"`
+	Password     string `env:"DB_PASS"`
+	DatabaseName string `env:"DB_NAME"`
+	Debug        bool   `env:"DB_DEBUG,default=false"`
+}
+
+type IconConfig struct {
+	Store     string `env:"ICON_STORE,default=database"`
+	Directory string `env:"ICON_DIRECTORY,default=data/icons"`
+	MaxBytes  int64  `env:"ICON_MAX_BYTES,default=262144"`
+}
+
+// IdempotencyConfig controls how long responses are replayed for an idempotency key, and how often the
+// expired keys are deleted.
+type IdempotencyConfig struct {
+	Window        time.Duration `env:"IDEMPOTENCY_WINDOW,default=24h"`
+	SweepInterval time.Duration `env:"IDEMPOTENCY_SWEEP_INTERVAL,default=1h"`
+}
+
+// TrashConfig controls how long deleted habits can be restored before they are purged, and how often the
+// trash is purged.
+type TrashConfig struct {
+	Retention     time.Duration `env:"TRASH_RETENTION,default=720h"`
+	PurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL,default=1h"`
 }
 
 func New() *Config {
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"habitgobackend/cmd/config"
+	_ "habitgobackend/migrations"
 	"log"
 	"os"
 
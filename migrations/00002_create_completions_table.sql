@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS completions (
+    id UUID PRIMARY KEY,
+    habit_id UUID NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    completed_at TIMESTAMPTZ NOT NULL,
+    note TEXT
+);
+
+CREATE INDEX IF NOT EXISTS completions_habit_id_completed_at_idx ON completions (habit_id, completed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS completions;
+-- +goose StatementEnd
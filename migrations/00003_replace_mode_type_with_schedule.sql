@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN schedule_type TEXT NOT NULL DEFAULT 'daily',
+    ADD COLUMN schedule_times INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN schedule_weekdays INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN schedule_interval INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN schedule_day_of_month INTEGER NOT NULL DEFAULT 0;
+
+-- Unrecognised mode types fall back to the daily default.
+UPDATE habits SET schedule_type = 'times_per_week', schedule_times = 1 WHERE lower(trim(mode_type)) = 'weekly';
+UPDATE habits SET schedule_type = 'monthly', schedule_day_of_month = 1 WHERE lower(trim(mode_type)) = 'monthly';
+UPDATE habits SET schedule_type = 'every_n_days', schedule_interval = 365 WHERE lower(trim(mode_type)) = 'yearly';
+
+ALTER TABLE habits
+    DROP COLUMN mode_type,
+    ADD CONSTRAINT habits_schedule_check CHECK (
+        (schedule_type = 'daily')
+        OR (schedule_type = 'times_per_week' AND schedule_times BETWEEN 1 AND 7)
+        OR (schedule_type = 'weekdays' AND schedule_weekdays BETWEEN 1 AND 127)
+        OR (schedule_type = 'every_n_days' AND schedule_interval >= 1)
+        OR (schedule_type = 'monthly' AND schedule_day_of_month BETWEEN 1 AND 31)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE habits ADD COLUMN mode_type TEXT;
+
+UPDATE habits SET mode_type = CASE
+    WHEN schedule_type = 'times_per_week' AND schedule_times = 1 THEN 'weekly'
+    WHEN schedule_type = 'monthly' AND schedule_day_of_month = 1 THEN 'monthly'
+    WHEN schedule_type = 'every_n_days' AND schedule_interval = 365 THEN 'yearly'
+    WHEN schedule_type = 'daily' THEN 'daily'
+    ELSE schedule_type
+END;
+
+ALTER TABLE habits
+    DROP CONSTRAINT habits_schedule_check,
+    DROP COLUMN schedule_type,
+    DROP COLUMN schedule_times,
+    DROP COLUMN schedule_weekdays,
+    DROP COLUMN schedule_interval,
+    DROP COLUMN schedule_day_of_month;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Habits created before users existed have no owner until the first user registers, see
+-- 00017_assign_ownerless_habits.sql.
+ALTER TABLE habits ADD COLUMN user_id UUID REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS habits_user_id_idx ON habits (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE habits DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS habits_user_id_created_at_idx ON habits (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS habits_user_id_description_idx ON habits (user_id, description, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS habits_user_id_description_idx;
+DROP INDEX IF EXISTS habits_user_id_created_at_idx;
+
+ALTER TABLE habits
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at;
+-- +goose StatementEnd
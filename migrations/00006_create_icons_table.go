@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"habitgobackend/cmd/api/resource/icon"
+
+	"github.com/pressly/goose/v3"
+)
+
+// The icons migration is written in Go to tell the content type of the legacy inline icons the same way
+// uploads are checked, instead of guessing it in SQL.
+func init() {
+	goose.AddMigrationContext(upCreateIconsTable, downCreateIconsTable)
+}
+
+func upCreateIconsTable(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS icons (
+			id TEXT PRIMARY KEY,
+			content_type TEXT NOT NULL,
+			size BIGINT NOT NULL,
+			data BYTEA NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		ALTER TABLE habits ADD COLUMN icon_id TEXT REFERENCES icons (id);`); err != nil {
+		return err
+	}
+
+	inlineIcons, err := legacyIcons(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Existing inline icons are moved into the icons table, icons which are not valid base64 or neither a
+	// PNG nor an SVG image are dropped.
+	for habitID, dataURL := range inlineIcons {
+		inlineIcon, err := icon.FromDataURL(dataURL)
+		if err != nil {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO icons (id, content_type, size, data) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO NOTHING`,
+			inlineIcon.ID, inlineIcon.ContentType, inlineIcon.Size, inlineIcon.Data); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE habits SET icon_id = $1 WHERE id = $2",
+			inlineIcon.ID, habitID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "ALTER TABLE habits DROP COLUMN icon_base64")
+	return err
+}
+
+// legacyIcons returns the inline icons of the habits by habit ID, they are read before any of them is moved
+// as a transaction cannot run statements while rows are still being read.
+func legacyIcons(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, icon_base64 FROM habits WHERE icon_base64 IS NOT NULL AND icon_base64 <> ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inlineIcons := map[string]string{}
+	for rows.Next() {
+		var habitID, dataURL string
+		if err := rows.Scan(&habitID, &dataURL); err != nil {
+			return nil, err
+		}
+		inlineIcons[habitID] = dataURL
+	}
+	return inlineIcons, rows.Err()
+}
+
+func downCreateIconsTable(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE habits ADD COLUMN icon_base64 TEXT;
+
+		UPDATE habits
+		SET icon_base64 = 'data:' || icons.content_type || ';base64,' || replace(encode(icons.data, 'base64'), E'\n', '')
+		FROM icons
+		WHERE habits.icon_id = icons.id;
+
+		ALTER TABLE habits DROP COLUMN icon_id;
+
+		DROP TABLE IF EXISTS icons;`)
+	return err
+}
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_habits_deleted_at ON habits (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_habits_deleted_at;
+
+-- Habits in the trash were deleted as far as clients are concerned.
+DELETE FROM habits WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE habits
+    DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active',
+    ADD CONSTRAINT habits_status_check CHECK (status IN ('active', 'paused', 'archived'));
+
+CREATE INDEX IF NOT EXISTS habits_user_id_status_idx ON habits (user_id, status);
+
+CREATE TABLE IF NOT EXISTS habit_pauses (
+    id UUID PRIMARY KEY,
+    habit_id UUID NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS habit_pauses_habit_id_started_at_idx ON habit_pauses (habit_id, started_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS habit_pauses;
+
+DROP INDEX IF EXISTS habits_user_id_status_idx;
+
+ALTER TABLE habits
+    DROP CONSTRAINT habits_status_check,
+    DROP COLUMN status;
+-- +goose StatementEnd
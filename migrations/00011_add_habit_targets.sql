@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN target_value DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN target_unit TEXT NOT NULL DEFAULT '',
+    ADD COLUMN target_aggregation TEXT NOT NULL DEFAULT '',
+    ADD CONSTRAINT habits_target_check CHECK (
+        (target_value = 0)
+        OR (target_value > 0 AND target_aggregation IN ('sum', 'max', 'count'))
+    );
+
+ALTER TABLE completions
+    ADD COLUMN amount DOUBLE PRECISION NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE completions
+    DROP COLUMN amount;
+
+ALTER TABLE habits
+    DROP CONSTRAINT habits_target_check,
+    DROP COLUMN target_aggregation,
+    DROP COLUMN target_unit,
+    DROP COLUMN target_value;
+-- +goose StatementEnd
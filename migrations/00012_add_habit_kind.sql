@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'build',
+    ADD CONSTRAINT habits_kind_check CHECK (
+        (kind = 'build')
+        OR (kind = 'avoid' AND target_value = 0)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE habits
+    DROP CONSTRAINT habits_kind_check,
+    DROP COLUMN kind;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN intention_time TEXT NOT NULL DEFAULT '',
+    ADD COLUMN intention_location TEXT NOT NULL DEFAULT '',
+    ADD COLUMN intention_cue TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE habits
+    DROP COLUMN intention_cue,
+    DROP COLUMN intention_location,
+    DROP COLUMN intention_time;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN anchor_id UUID REFERENCES habits (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS habits_anchor_id_idx ON habits (anchor_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS habits_anchor_id_idx;
+
+ALTER TABLE habits
+    DROP COLUMN anchor_id;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scorecard_entries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    behaviour TEXT NOT NULL,
+    rating TEXT NOT NULL,
+    habit_id UUID REFERENCES habits (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS scorecard_entries_user_id_position_idx ON scorecard_entries (user_id, position);
+CREATE INDEX IF NOT EXISTS scorecard_entries_deleted_at_idx ON scorecard_entries (deleted_at);
+
+CREATE TABLE IF NOT EXISTS scorecard_rating_changes (
+    id UUID PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES scorecard_entries (id) ON DELETE CASCADE,
+    rating TEXT NOT NULL,
+    rated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scorecard_rating_changes_entry_id_rated_at_idx ON scorecard_rating_changes (entry_id, rated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scorecard_rating_changes;
+
+DROP TABLE IF EXISTS scorecard_entries;
+-- +goose StatementEnd
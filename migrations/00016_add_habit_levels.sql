@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE habits
+    ADD COLUMN two_minute_version TEXT NOT NULL DEFAULT '',
+    ADD COLUMN levels JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN level INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS habit_level_changes (
+    id UUID PRIMARY KEY,
+    habit_id UUID NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    from_level INTEGER NOT NULL,
+    to_level INTEGER NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS habit_level_changes_habit_id_changed_at_idx ON habit_level_changes (habit_id, changed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS habit_level_changes;
+
+ALTER TABLE habits
+    DROP COLUMN level,
+    DROP COLUMN levels,
+    DROP COLUMN two_minute_version;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Habits created before users existed belonged to whoever ran the API then, they are given to the first
+-- user who registered. Without users they stay ownerless until the first user registers, see
+-- user.Repository.CreateUser.
+UPDATE habits
+SET user_id = (SELECT id FROM users ORDER BY created_at, id LIMIT 1)
+WHERE user_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Which habits had no owner is not kept, they stay with the user they were given to.
+SELECT 1;
+-- +goose StatementEnd
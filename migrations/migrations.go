@@ -0,0 +1,43 @@
+// Package migrations embeds the goose migrations, so that the API knows which version the database
+// should be migrated to. The migrations in this directory are for Postgres, the ones in sqlite/ build the
+// same schema for SQLite and are run by the API itself when it starts. Postgres migrations which need Go
+// are registered with goose when the package is imported.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite returns the SQLite migrations.
+func SQLite() (fs.FS, error) {
+	return fs.Sub(sqliteFS, "sqlite")
+}
+
+// Version returns the version of the newest SQL migration of migrations, either FS or SQLite. The SQLite
+// migrations are numbered on their own, a schema change needs a migration for both databases but not with
+// the same version.
+func Version(migrations fs.FS) (int64, error) {
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
@@ -0,0 +1,157 @@
+-- The schema of the Postgres migrations up to 00016 for SQLite, which cannot run them as they are. Every later
+-- Postgres migration needs a SQLite migration with the same version.
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS icons (
+    id TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS habits (
+    id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL DEFAULT 'build',
+    description TEXT,
+    colour_hex TEXT,
+    icon_id TEXT REFERENCES icons (id),
+    schedule_type TEXT NOT NULL DEFAULT 'daily',
+    schedule_times INTEGER NOT NULL DEFAULT 0,
+    schedule_weekdays INTEGER NOT NULL DEFAULT 0,
+    schedule_interval INTEGER NOT NULL DEFAULT 0,
+    schedule_day_of_month INTEGER NOT NULL DEFAULT 0,
+    target_value REAL NOT NULL DEFAULT 0,
+    target_unit TEXT NOT NULL DEFAULT '',
+    target_aggregation TEXT NOT NULL DEFAULT '',
+    intention_time TEXT NOT NULL DEFAULT '',
+    intention_location TEXT NOT NULL DEFAULT '',
+    intention_cue TEXT NOT NULL DEFAULT '',
+    anchor_id TEXT REFERENCES habits (id) ON DELETE SET NULL,
+    two_minute_version TEXT NOT NULL DEFAULT '',
+    levels JSON NOT NULL DEFAULT '[]',
+    level INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
+    CONSTRAINT habits_schedule_check CHECK (
+        (schedule_type = 'daily')
+        OR (schedule_type = 'times_per_week' AND schedule_times BETWEEN 1 AND 7)
+        OR (schedule_type = 'weekdays' AND schedule_weekdays BETWEEN 1 AND 127)
+        OR (schedule_type = 'every_n_days' AND schedule_interval >= 1)
+        OR (schedule_type = 'monthly' AND schedule_day_of_month BETWEEN 1 AND 31)
+    ),
+    CONSTRAINT habits_target_check CHECK (
+        (target_value = 0)
+        OR (target_value > 0 AND target_aggregation IN ('sum', 'max', 'count'))
+    ),
+    CONSTRAINT habits_kind_check CHECK (
+        (kind = 'build')
+        OR (kind = 'avoid' AND target_value = 0)
+    ),
+    CONSTRAINT habits_status_check CHECK (status IN ('active', 'paused', 'archived'))
+);
+
+CREATE INDEX IF NOT EXISTS habits_user_id_idx ON habits (user_id);
+CREATE INDEX IF NOT EXISTS habits_user_id_created_at_idx ON habits (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS habits_user_id_description_idx ON habits (user_id, description, id);
+CREATE INDEX IF NOT EXISTS habits_user_id_status_idx ON habits (user_id, status);
+CREATE INDEX IF NOT EXISTS habits_anchor_id_idx ON habits (anchor_id);
+CREATE INDEX IF NOT EXISTS idx_habits_deleted_at ON habits (deleted_at);
+
+CREATE TABLE IF NOT EXISTS completions (
+    id TEXT PRIMARY KEY,
+    habit_id TEXT NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    completed_at DATETIME NOT NULL,
+    note TEXT,
+    amount REAL NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS completions_habit_id_completed_at_idx ON completions (habit_id, completed_at);
+
+CREATE TABLE IF NOT EXISTS habit_pauses (
+    id TEXT PRIMARY KEY,
+    habit_id TEXT NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS habit_pauses_habit_id_started_at_idx ON habit_pauses (habit_id, started_at);
+
+CREATE TABLE IF NOT EXISTS habit_level_changes (
+    id TEXT PRIMARY KEY,
+    habit_id TEXT NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    from_level INTEGER NOT NULL,
+    to_level INTEGER NOT NULL,
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS habit_level_changes_habit_id_changed_at_idx ON habit_level_changes (habit_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    location TEXT NOT NULL DEFAULT '',
+    created_id TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    etag TEXT NOT NULL DEFAULT '',
+    body BLOB,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS scorecard_entries (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    behaviour TEXT NOT NULL,
+    rating TEXT NOT NULL,
+    habit_id TEXT REFERENCES habits (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS scorecard_entries_user_id_position_idx ON scorecard_entries (user_id, position);
+CREATE INDEX IF NOT EXISTS scorecard_entries_deleted_at_idx ON scorecard_entries (deleted_at);
+
+CREATE TABLE IF NOT EXISTS scorecard_rating_changes (
+    id TEXT PRIMARY KEY,
+    entry_id TEXT NOT NULL REFERENCES scorecard_entries (id) ON DELETE CASCADE,
+    rating TEXT NOT NULL,
+    rated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scorecard_rating_changes_entry_id_rated_at_idx ON scorecard_rating_changes (entry_id, rated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scorecard_rating_changes;
+DROP TABLE IF EXISTS scorecard_entries;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS habit_level_changes;
+DROP TABLE IF EXISTS habit_pauses;
+DROP TABLE IF EXISTS completions;
+DROP TABLE IF EXISTS habits;
+DROP TABLE IF EXISTS icons;
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd
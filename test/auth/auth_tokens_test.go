@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/auth"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"habitgobackend/test/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var secret = []byte("test-secret")
+
+func TestTokens_IssueAndParse(testing *testing.T) {
+	testing.Parallel()
+
+	tokens := auth.NewTokens(secret, time.Minute, time.Hour)
+	userID := uuid.New()
+
+	issued, err := tokens.Issue(userID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, issued.TokenType, "Bearer")
+	util.IsEqual(testing, issued.ExpiresIn, 60)
+
+	accessUserID, err := tokens.ParseAccessToken(issued.AccessToken)
+	util.NoError(testing, err)
+	util.IsEqual(testing, accessUserID, userID)
+
+	refreshUserID, err := tokens.ParseRefreshToken(issued.RefreshToken)
+	util.NoError(testing, err)
+	util.IsEqual(testing, refreshUserID, userID)
+}
+
+func TestTokens_RejectsInvalidTokens(testing *testing.T) {
+	testing.Parallel()
+
+	tokens := auth.NewTokens(secret, time.Minute, time.Hour)
+	issued, err := tokens.Issue(uuid.New())
+	util.NoError(testing, err)
+
+	expired, err := auth.NewTokens(secret, -time.Minute, -time.Minute).Issue(uuid.New())
+	util.NoError(testing, err)
+
+	otherSecret, err := auth.NewTokens([]byte("other-secret"), time.Minute, time.Hour).Issue(uuid.New())
+	util.NoError(testing, err)
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, auth.Claims{
+		TokenType: "access",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "habitgobackend",
+			Subject:   uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	util.NoError(testing, err)
+
+	for name, token := range map[string]string{
+		"refresh token used as access token": issued.RefreshToken,
+		"expired token":                      expired.AccessToken,
+		"token signed with another secret":   otherSecret.AccessToken,
+		"unsigned token":                     unsigned,
+		"tampered token":                     issued.AccessToken + "x",
+		"malformed token":                    "not-a-token",
+	} {
+		if _, err := tokens.ParseAccessToken(token); err == nil {
+			testing.Errorf("%s: expected the access token to be rejected", name)
+		}
+	}
+
+	if _, err := tokens.ParseRefreshToken(issued.AccessToken); err == nil {
+		testing.Errorf("expected an access token to be rejected as refresh token")
+	}
+}
+
+func TestTokens_Middleware(testing *testing.T) {
+	testing.Parallel()
+
+	tokens := auth.NewTokens(secret, time.Minute, time.Hour)
+	userID := uuid.New()
+	issued, err := tokens.Issue(userID)
+	util.NoError(testing, err)
+
+	var calledWith uuid.UUID
+	handler := tokens.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calledWith = identity.UserID(r.Context())
+	}))
+
+	for authorization, expectedStatus := range map[string]int{
+		"":                                   http.StatusUnauthorized,
+		"Basic dXNlcjpwYXNz":                 http.StatusUnauthorized,
+		"Bearer " + issued.RefreshToken:      http.StatusUnauthorized,
+		"Bearer " + issued.AccessToken + "x": http.StatusUnauthorized,
+		"Bearer " + issued.AccessToken:       http.StatusOK,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/v1/habits", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		util.IsEqual(testing, recorder.Code, expectedStatus)
+	}
+
+	util.IsEqual(testing, calledWith, userID)
+}
@@ -0,0 +1,105 @@
+package error
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"habitgobackend/cmd/api/config/validation"
+	e "habitgobackend/cmd/api/resource/common/error"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func decodeProblem(testing *testing.T, recorder *httptest.ResponseRecorder) e.Problem {
+	util.IsEqual(testing, recorder.Header().Get("Content-Type"), e.ContentType)
+
+	problem := e.Problem{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&problem))
+	return problem
+}
+
+func TestProblem_NotFound(testing *testing.T) {
+	testing.Parallel()
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/habits/42", nil)
+	request = request.WithContext(context.WithValue(request.Context(), middleware.RequestIDKey, "request-1"))
+	recorder := httptest.NewRecorder()
+
+	e.NotFound(recorder, request, e.HabitNotFound)
+
+	util.IsEqual(testing, recorder.Code, http.StatusNotFound)
+	problem := decodeProblem(testing, recorder)
+	util.IsEqual(testing, problem.Type, e.HabitNotFound.Type)
+	util.IsEqual(testing, problem.Title, e.HabitNotFound.Title)
+	util.IsEqual(testing, problem.Status, http.StatusNotFound)
+	util.IsEqual(testing, problem.Instance, "/v1/habits/42")
+	util.IsEqual(testing, problem.RequestID, "request-1")
+	util.IsEqual(testing, len(problem.Errors), 0)
+}
+
+func TestProblem_ValidationErrors(testing *testing.T) {
+	testing.Parallel()
+
+	jsonHabit := habit.JsonHabit{
+		ColourHex: "#000000",
+		IconID:    "not-an-icon",
+		Schedule:  &habit.JsonSchedule{Type: habit.ScheduleTimesPerWeek, Times: 9, Interval: 3},
+	}
+	err := validation.New().Struct(jsonHabit)
+
+	recorder := httptest.NewRecorder()
+	e.ValidationErrors(recorder, httptest.NewRequest(http.MethodPost, "/v1/habits", nil), err)
+
+	util.IsEqual(testing, recorder.Code, http.StatusUnprocessableEntity)
+	problem := decodeProblem(testing, recorder)
+	util.IsEqual(testing, problem.Type, e.ValidationFailure.Type)
+
+	messages := make(map[string]e.FieldError)
+	for _, fieldError := range problem.Errors {
+		messages[fieldError.Field] = fieldError
+	}
+	util.IsEqual(testing, len(messages), 4)
+	util.IsEqual(testing, messages["description"].Message, "is required")
+	util.IsEqual(testing, messages["iconId"].Message, "must be exactly 64 characters long")
+	util.IsEqual(testing, messages["schedule.times"].Rule, "max")
+	util.IsEqual(testing, messages["schedule.times"].Param, "7")
+	util.IsEqual(testing, messages["schedule.times"].Message, "must be at most 7")
+	util.IsEqual(testing, messages["schedule.interval"].Message, "is not allowed here")
+}
+
+func TestProblem_ConditionalValidationErrors(testing *testing.T) {
+	testing.Parallel()
+
+	jsonHabit := habit.JsonHabit{
+		Kind:        habit.KindAvoid,
+		Description: "Smoke",
+		ColourHex:   "#000000",
+		IconID:      "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+		ModeType:    "fortnightly",
+		Target:      &habit.JsonTarget{Value: 1, Aggregation: habit.AggregationCount},
+		TwoMinute:   "Hold a cigarette",
+		Levels:      []string{"One a day"},
+	}
+	err := validation.New().Struct(jsonHabit)
+
+	messages := make(map[string]e.FieldError)
+	for _, fieldError := range e.FieldErrors(err) {
+		messages[fieldError.Field] = fieldError
+	}
+	util.IsEqual(testing, len(messages), 4)
+	util.IsEqual(testing, messages["modeType"].Rule, "oneofci")
+	util.IsEqual(testing, messages["modeType"].Message, "must be one of daily, weekly, monthly, yearly, in any case")
+	util.IsEqual(testing, messages["target"].Rule, "excluded_if")
+	util.IsEqual(testing, messages["target"].Message, "is not allowed when kind is avoid")
+	util.IsEqual(testing, messages["twoMinuteVersion"].Message, "is not allowed when kind is avoid")
+	util.IsEqual(testing, messages["levels"].Message, "is not allowed when kind is avoid")
+}
+
+func TestFieldErrors_OtherErrors(testing *testing.T) {
+	testing.Parallel()
+
+	util.IsEqual(testing, len(e.FieldErrors(context.Canceled)), 0)
+}
@@ -0,0 +1,109 @@
+package habit
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestCompletionRepository_GetCompletions(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewCompletionRepository(database)
+
+	habitID := uuid.New()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	expectedCompletions := habit.Completions{
+		{ID: uuid.New(), HabitID: habitID, CompletedAt: from.Add(time.Hour), Note: "Morning run"},
+		{ID: uuid.New(), HabitID: habitID, CompletedAt: from.AddDate(0, 0, 1), Note: ""},
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "habit_id", "completed_at", "note"}).
+		AddRow(expectedCompletions[0].ID, habitID, expectedCompletions[0].CompletedAt, expectedCompletions[0].Note).
+		AddRow(expectedCompletions[1].ID, habitID, expectedCompletions[1].CompletedAt, expectedCompletions[1].Note)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"completions\" WHERE (.+) ORDER BY completed_at").
+		WithArgs(habitID, from, to).
+		WillReturnRows(rows)
+
+	completions, err := repository.GetCompletions(habitID, from, to)
+	util.NoError(testing, err)
+
+	util.IsEqual(testing, len(completions), 2)
+	for i, completion := range completions {
+		util.IsEqual(testing, completion.ID, expectedCompletions[i].ID)
+		util.IsEqual(testing, completion.HabitID, expectedCompletions[i].HabitID)
+		util.IsEqual(testing, completion.CompletedAt.Equal(expectedCompletions[i].CompletedAt), true)
+		util.IsEqual(testing, completion.Note, expectedCompletions[i].Note)
+	}
+}
+
+func TestCompletionRepository_GetCompletionsOpenRange(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewCompletionRepository(database)
+
+	habitID := uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM \"completions\" WHERE habit_id = \\$1 ORDER BY completed_at").
+		WithArgs(habitID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "habit_id", "completed_at", "note"}))
+
+	completions, err := repository.GetCompletions(habitID, time.Time{}, time.Time{})
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(completions), 0)
+}
+
+func TestCompletionRepository_CreateCompletion(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewCompletionRepository(database)
+
+	completion := &habit.Completion{ID: uuid.New(), HabitID: uuid.New(), CompletedAt: time.Now(), Note: "Done"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO \"completions\" ").
+		WithArgs(completion.ID, completion.HabitID, util.AnyTime{}, 0.0, "Done").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := repository.CreateCompletion(completion)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, completion.ID)
+}
+
+func TestCompletionRepository_DeleteCompletion(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewCompletionRepository(database)
+
+	habitID := uuid.New()
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^DELETE FROM \"completions\" WHERE (.+)").
+		WithArgs(id, habitID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := repository.DeleteCompletion(habitID, id)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result, 1)
+}
@@ -0,0 +1,1249 @@
+package habit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"habitgobackend/cmd/api/config/validation"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/test/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIcon     = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`
+	iconMaxBytes = 1024
+)
+
+var errConnection = errors.New("connection refused")
+
+// failingStore fails every call, as a store does when the database is down.
+type failingStore struct{}
+
+func (failingStore) GetHabits(uuid.UUID, habit.HabitQuery) (habit.Habits, error) {
+	return nil, errConnection
+}
+func (failingStore) GetHabit(uuid.UUID, uuid.UUID) (*habit.Habit, error)  { return nil, errConnection }
+func (failingStore) GetAllHabits(uuid.UUID) (habit.Habits, error)         { return nil, errConnection }
+func (failingStore) CreateHabit(*habit.Habit) (*habit.Habit, error)       { return nil, errConnection }
+func (failingStore) UpdateHabit(*habit.Habit) (int64, error)              { return 0, errConnection }
+func (failingStore) PatchHabit(*habit.Habit, []string) (int64, error)     { return 0, errConnection }
+func (failingStore) DeleteHabit(uuid.UUID, uuid.UUID, int) (int64, error) { return 0, errConnection }
+func (failingStore) GetTrash(uuid.UUID) (habit.Habits, error)             { return nil, errConnection }
+func (failingStore) RestoreHabit(uuid.UUID, uuid.UUID) (*habit.Habit, error) {
+	return nil, errConnection
+}
+func (failingStore) PurgeHabits(time.Time) ([]uuid.UUID, error)           { return nil, errConnection }
+func (failingStore) Transaction(func(store habit.HabitStore) error) error { return errConnection }
+func (store failingStore) Completions() habit.CompletionStore             { return store }
+func (store failingStore) Pauses() habit.PauseStore                       { return store }
+func (store failingStore) Levels() habit.LevelStore                       { return store }
+
+func (failingStore) GetCompletions(uuid.UUID, time.Time, time.Time) (habit.Completions, error) {
+	return nil, errConnection
+}
+func (failingStore) GetHabitsCompletions([]uuid.UUID, time.Time) (map[uuid.UUID]habit.Completions, error) {
+	return nil, errConnection
+}
+func (failingStore) CreateCompletion(*habit.Completion) (*habit.Completion, error) {
+	return nil, errConnection
+}
+func (failingStore) DeleteCompletion(uuid.UUID, uuid.UUID) (int64, error) { return 0, errConnection }
+func (failingStore) GetPauses(uuid.UUID) (habit.Pauses, error)            { return nil, errConnection }
+func (failingStore) GetHabitsPauses([]uuid.UUID) (map[uuid.UUID]habit.Pauses, error) {
+	return nil, errConnection
+}
+func (failingStore) StartPause(*habit.Pause) (*habit.Pause, error)         { return nil, errConnection }
+func (failingStore) EndPause(uuid.UUID, time.Time) (int64, error)          { return 0, errConnection }
+func (failingStore) GetLevelChanges(uuid.UUID) (habit.LevelChanges, error) { return nil, errConnection }
+func (failingStore) CreateLevelChange(*habit.LevelChange) (*habit.LevelChange, error) {
+	return nil, errConnection
+}
+
+// fixture is a habit API with a single user owning a daily habit with one completion, and a habit with a
+// schedule the API no longer understands.
+type fixture struct {
+	api          *habit.Api
+	database     *gorm.DB
+	userID       uuid.UUID
+	habitID      uuid.UUID
+	brokenID     uuid.UUID
+	completionID uuid.UUID
+	iconID       string
+}
+
+type fixtureOption int
+
+const (
+	storeDown fixtureOption = iota + 1
+	databaseDown
+)
+
+func newFixture(testing *testing.T, option fixtureOption) fixture {
+	database := util.NewMemoryDatabase(testing)
+
+	iconStore := icon.NewDatabaseStore(database)
+	testIcon, err := icon.FromBytes([]byte(testIcon))
+	util.NoError(testing, err)
+	_, err = iconStore.Save(context.Background(), testIcon)
+	util.NoError(testing, err)
+
+	f := fixture{
+		database:     database,
+		userID:       util.NewUser(testing, database),
+		habitID:      uuid.New(),
+		brokenID:     uuid.New(),
+		completionID: uuid.New(),
+		iconID:       testIcon.ID,
+	}
+
+	store := habit.NewRepository(database)
+	_, err = store.CreateHabit(&habit.Habit{ID: f.habitID, UserID: f.userID, Description: "Read", ColourHex: "#000000",
+		IconID: f.iconID, Schedule: habit.Schedule{Type: habit.ScheduleDaily}})
+	util.NoError(testing, err)
+	// The schedule check of the database keeps the API from storing a schedule it does not understand, the
+	// broken habit stands for a row written by a newer version of the API.
+	util.NoError(testing, database.Exec("PRAGMA ignore_check_constraints = ON").Error)
+	_, err = store.CreateHabit(&habit.Habit{ID: f.brokenID, UserID: f.userID, Description: "Broken", ColourHex: "#000000",
+		IconID: f.iconID, Schedule: habit.Schedule{Type: "fortnightly"}})
+	util.NoError(testing, err)
+	util.NoError(testing, database.Exec("PRAGMA ignore_check_constraints = OFF").Error)
+
+	_, err = habit.NewCompletionRepository(database).CreateCompletion(&habit.Completion{ID: f.completionID,
+		HabitID: f.habitID, CompletedAt: time.Now()})
+	util.NoError(testing, err)
+
+	var habitStore habit.HabitStore = store
+	switch option {
+	case storeDown:
+		habitStore = failingStore{}
+	case databaseDown:
+		sqlDatabase, err := database.DB()
+		util.NoError(testing, err)
+		util.NoError(testing, sqlDatabase.Close())
+	}
+
+	f.api = habit.New(habitStore, validation.New(), iconStore, iconMaxBytes)
+	return f
+}
+
+func (f fixture) habitBody(description string) string {
+	return `{"description":"` + description + `","colourHex":"#ffffff","iconId":"` + f.iconID + `","schedule":{"type":"daily"}}`
+}
+
+// inlineIconBody is the body of a habit sending its icon the legacy way, inline as a data URL.
+func inlineIconBody(f fixture, svg string) string {
+	return strings.Replace(f.habitBody("Read"), `"iconId":"`+f.iconID+`"`,
+		`"iconBase64":"data:image/svg+xml;base64,`+base64.StdEncoding.EncodeToString([]byte(svg))+`"`, 1)
+}
+
+func TestApi_Handlers(t *testing.T) {
+	unknownIcon := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name        string
+		option      fixtureOption
+		handler     func(*habit.Api, http.ResponseWriter, *http.Request)
+		method      string
+		target      string
+		contentType string
+		headers     map[string]string
+		body        func(fixture) string
+		params      func(fixture) map[string]string
+		status      int
+		problem     e.Problem
+	}{
+		{name: "get habit", handler: (*habit.Api).GetHabit, method: http.MethodGet, target: "/habits/id?include=streak",
+			params: habitParam, status: http.StatusOK},
+		{name: "get habit with invalid id", handler: (*habit.Api).GetHabit, method: http.MethodGet, target: "/habits/id",
+			params: param("id", "not-a-uuid"), status: http.StatusBadRequest, problem: e.InvalidUrlRequest},
+		{name: "get missing habit", handler: (*habit.Api).GetHabit, method: http.MethodGet, target: "/habits/id",
+			params: param("id", uuid.NewString()), status: http.StatusNotFound, problem: e.HabitNotFound},
+		{name: "get habit with unsupported schedule", handler: (*habit.Api).GetHabit, method: http.MethodGet,
+			target: "/habits/id?include=streak", params: brokenParam, status: http.StatusUnprocessableEntity,
+			problem: e.UnsupportedSchedule},
+		{name: "get habit while store is down", option: storeDown, handler: (*habit.Api).GetHabit, method: http.MethodGet,
+			target: "/habits/id", params: habitParam, status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "get unmodified habit", handler: (*habit.Api).GetHabit, method: http.MethodGet, target: "/habits/id",
+			headers: ifNoneMatch(`W/"1"`), params: habitParam, status: http.StatusNotModified},
+		{name: "get modified habit", handler: (*habit.Api).GetHabit, method: http.MethodGet, target: "/habits/id",
+			headers: ifNoneMatch(`"2"`), params: habitParam, status: http.StatusOK},
+		{name: "get unmodified habit with streak", handler: (*habit.Api).GetHabit, method: http.MethodGet,
+			target: "/habits/id?include=streak", headers: ifNoneMatch(`"1"`), params: habitParam, status: http.StatusOK},
+
+		{name: "create habit", handler: (*habit.Api).CreateHabit, method: http.MethodPost, target: "/habits",
+			body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusCreated},
+		{name: "create habit with malformed body", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: text(`{"description":`), status: http.StatusBadRequest, problem: e.JsonDecodeFailure},
+		{name: "create invalid habit", handler: (*habit.Api).CreateHabit, method: http.MethodPost, target: "/habits",
+			body: func(f fixture) string { return f.habitBody("") }, status: http.StatusUnprocessableEntity,
+			problem: e.ValidationFailure},
+		{name: "create habit with invalid target", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Drink"), `"schedule"`, `"target":{"value":0,"aggregation":"sum"},"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create avoided habit with target", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Smoke"), `"schedule"`,
+					`"kind":"avoid","target":{"value":1,"aggregation":"count"},"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create habit with inline icon", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string { return inlineIconBody(f, testIcon) },
+			status: http.StatusCreated},
+		{name: "create habit with inline icon above the upload limit", handler: (*habit.Api).CreateHabit,
+			method: http.MethodPost, target: "/habits", body: func(f fixture) string {
+				return inlineIconBody(f, strings.Replace(testIcon, "/>", ">"+strings.Repeat(" ", iconMaxBytes)+"</svg>", 1))
+			}, status: http.StatusRequestEntityTooLarge, problem: e.IconTooLarge},
+		{name: "create habit with capitalised legacy mode type", handler: (*habit.Api).CreateHabit,
+			method: http.MethodPost, target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Read"), `"schedule":{"type":"daily"}`, `"modeType":"Weekly"`, 1)
+			}, status: http.StatusCreated},
+		{name: "create habit with intention", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Read"), `"schedule"`,
+					`"intention":{"time":"07:30","location":"the kitchen"},"schedule"`, 1)
+			}, status: http.StatusCreated},
+		{name: "create habit with invalid intention time", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Read"), `"schedule"`, `"intention":{"time":"7.30pm"},"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create habit with empty intention", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Read"), `"schedule"`, `"intention":{},"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create habit with levels", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Run"), `"schedule"`,
+					`"twoMinuteVersion":"Put on my shoes","levels":["Run 1 km","Run 5 km"],"schedule"`, 1)
+			}, status: http.StatusCreated},
+		{name: "create habit with empty level", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Run"), `"schedule"`, `"levels":["Run 1 km",""],"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create avoided habit with levels", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Smoke"), `"schedule"`,
+					`"kind":"avoid","levels":["One a day"],"schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create stacked habit", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Write"), `"schedule"`, `"anchorId":"`+f.habitID.String()+`","schedule"`, 1)
+			}, status: http.StatusCreated},
+		{name: "create habit with unknown anchor", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Write"), `"schedule"`, `"anchorId":"`+uuid.NewString()+`","schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.UnknownAnchor},
+		{name: "create habit with invalid anchor", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Write"), `"schedule"`, `"anchorId":"read","schedule"`, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create habit with unknown icon", handler: (*habit.Api).CreateHabit, method: http.MethodPost,
+			target: "/habits", body: func(f fixture) string {
+				return strings.Replace(f.habitBody("Write"), f.iconID, unknownIcon, 1)
+			}, status: http.StatusUnprocessableEntity, problem: e.UnknownIcon},
+		{name: "create habit while store is down", option: storeDown, handler: (*habit.Api).CreateHabit,
+			method: http.MethodPost, target: "/habits", body: func(f fixture) string { return f.habitBody("Write") },
+			status: http.StatusInternalServerError, problem: e.CreateFailure},
+
+		{name: "batch", handler: (*habit.Api).Batch, method: http.MethodPost, target: "/habits:batch",
+			body:   func(f fixture) string { return `{"operations":[{"op":"create","habit":` + f.habitBody("Write") + `}]}` },
+			status: http.StatusOK},
+		{name: "batch without operations", handler: (*habit.Api).Batch, method: http.MethodPost,
+			target: "/habits:batch", body: text(`{"operations":[]}`), status: http.StatusUnprocessableEntity,
+			problem: e.ValidationFailure},
+		{name: "batch with malformed body", handler: (*habit.Api).Batch, method: http.MethodPost,
+			target: "/habits:batch", body: text(`{"operations":`), status: http.StatusBadRequest,
+			problem: e.JsonDecodeFailure},
+		{name: "batch while store is down", option: storeDown, handler: (*habit.Api).Batch, method: http.MethodPost,
+			target: "/habits:batch", body: text(`{"operations":[{"op":"delete","id":"` + uuid.NewString() + `"}]}`),
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+
+		{name: "get habits", handler: (*habit.Api).GetHabits, method: http.MethodGet, target: "/habits?limit=1",
+			status: http.StatusOK},
+		{name: "get habits with invalid query", handler: (*habit.Api).GetHabits, method: http.MethodGet,
+			target: "/habits?limit=0", status: http.StatusBadRequest, problem: e.InvalidQueryParams},
+		{name: "get habits while store is down", option: storeDown, handler: (*habit.Api).GetHabits,
+			method: http.MethodGet, target: "/habits", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "get habits while database is down", option: databaseDown, handler: (*habit.Api).GetHabits,
+			method: http.MethodGet, target: "/habits", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+
+		{name: "update habit", handler: (*habit.Api).UpdateHabit, method: http.MethodPut, target: "/habits/id",
+			params: habitParam, body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusOK},
+		{name: "update habit with invalid id", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "update habit with malformed body", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", params: habitParam, body: text("[]"), status: http.StatusBadRequest,
+			problem: e.JsonDecodeFailure},
+		{name: "update missing habit", handler: (*habit.Api).UpdateHabit, method: http.MethodPut, target: "/habits/id",
+			params: param("id", uuid.NewString()), body: func(f fixture) string { return f.habitBody("Write") },
+			status: http.StatusNotFound, problem: e.HabitNotFound},
+		{name: "update habit with invalid body", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", params: habitParam, body: func(f fixture) string { return f.habitBody("") },
+			status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "update habit while store is down", option: storeDown, handler: (*habit.Api).UpdateHabit,
+			method: http.MethodPut, target: "/habits/id", params: habitParam,
+			body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "update habit with current etag", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", headers: ifMatch(`"1"`), params: habitParam,
+			body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusOK},
+		{name: "update habit with stale etag", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", headers: ifMatch(`"2"`), params: habitParam,
+			body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusPreconditionFailed,
+			problem: e.HabitModified},
+		{name: "update habit with weak etag", handler: (*habit.Api).UpdateHabit, method: http.MethodPut,
+			target: "/habits/id", headers: ifMatch(`W/"1"`), params: habitParam,
+			body: func(f fixture) string { return f.habitBody("Write") }, status: http.StatusPreconditionFailed,
+			problem: e.HabitModified},
+
+		{name: "merge patch habit", handler: (*habit.Api).PatchHabit, method: http.MethodPatch, target: "/habits/id",
+			contentType: habit.ContentTypeMergePatch, params: habitParam, body: text(`{"colourHex":"#ffffff"}`),
+			status: http.StatusOK},
+		{name: "json patch habit", handler: (*habit.Api).PatchHabit, method: http.MethodPatch, target: "/habits/id",
+			contentType: habit.ContentTypeJsonPatch, params: habitParam,
+			body:   text(`[{"op":"test","path":"/description","value":"Read"},{"op":"replace","path":"/description","value":"Write"}]`),
+			status: http.StatusOK},
+		{name: "patch habit with invalid id", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeMergePatch, params: param("id", "not-a-uuid"),
+			body: text(`{}`), status: http.StatusBadRequest, problem: e.InvalidUrlRequest},
+		{name: "patch habit with malformed patch", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeJsonPatch, params: habitParam,
+			body: text(`{"op":"replace"}`), status: http.StatusBadRequest, problem: e.InvalidPatch},
+		{name: "patch missing habit", handler: (*habit.Api).PatchHabit, method: http.MethodPatch, target: "/habits/id",
+			contentType: habit.ContentTypeMergePatch, params: param("id", uuid.NewString()), body: text(`{}`),
+			status: http.StatusNotFound, problem: e.HabitNotFound},
+		{name: "patch habit with failing test", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeJsonPatch, params: habitParam,
+			body: text(`[{"op":"test","path":"/description","value":"Write"}]`), status: http.StatusConflict,
+			problem: e.PatchConflict},
+		{name: "patch habit with plain json", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: "application/json", params: habitParam, body: text(`{}`),
+			status: http.StatusUnsupportedMediaType, problem: e.UnsupportedPatch},
+		{name: "patch habit into invalid habit", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeMergePatch, params: habitParam,
+			body: text(`{"description":null}`), status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "patch habit while store is down", option: storeDown, handler: (*habit.Api).PatchHabit,
+			method: http.MethodPatch, target: "/habits/id", contentType: habit.ContentTypeMergePatch,
+			params: habitParam, body: text(`{}`), status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "patch habit with stale etag", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeMergePatch, headers: ifMatch(`"0", "2"`),
+			params: habitParam, body: text(`{}`), status: http.StatusPreconditionFailed, problem: e.HabitModified},
+
+		{name: "delete habit", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete, target: "/habits/id",
+			params: habitParam, status: http.StatusOK},
+		{name: "delete habit with invalid id", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete,
+			target: "/habits/id", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "delete missing habit", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete,
+			target: "/habits/id", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "delete habit while store is down", option: storeDown, handler: (*habit.Api).DeleteHabit,
+			method: http.MethodDelete, target: "/habits/id", params: habitParam, status: http.StatusInternalServerError,
+			problem: e.DeleteFailure},
+		{name: "delete habit with any etag", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete,
+			target: "/habits/id", headers: ifMatch("*"), params: habitParam, status: http.StatusOK},
+		{name: "delete habit with stale etag", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete,
+			target: "/habits/id", headers: ifMatch(`"2"`), params: habitParam, status: http.StatusPreconditionFailed,
+			problem: e.HabitModified},
+		{name: "delete missing habit with etag", handler: (*habit.Api).DeleteHabit, method: http.MethodDelete,
+			target: "/habits/id", headers: ifMatch(`"1"`), params: param("id", uuid.NewString()),
+			status: http.StatusNotFound, problem: e.HabitNotFound},
+
+		{name: "get trash", handler: (*habit.Api).GetTrash, method: http.MethodGet, target: "/habits/trash",
+			status: http.StatusOK},
+		{name: "get trash while store is down", option: storeDown, handler: (*habit.Api).GetTrash,
+			method: http.MethodGet, target: "/habits/trash", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "restore habit which is not deleted", handler: (*habit.Api).RestoreHabit, method: http.MethodPost,
+			target: "/habits/id/restore", params: habitParam, status: http.StatusNotFound, problem: e.HabitNotFound},
+		{name: "restore habit with invalid id", handler: (*habit.Api).RestoreHabit, method: http.MethodPost,
+			target: "/habits/id/restore", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "restore habit while store is down", option: storeDown, handler: (*habit.Api).RestoreHabit,
+			method: http.MethodPost, target: "/habits/id/restore", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.UpdateFailure},
+
+		{name: "pause habit", handler: (*habit.Api).PauseHabit, method: http.MethodPost, target: "/habits/id/pause",
+			params: habitParam, status: http.StatusOK},
+		{name: "pause habit with invalid id", handler: (*habit.Api).PauseHabit, method: http.MethodPost,
+			target: "/habits/id/pause", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "pause missing habit", handler: (*habit.Api).PauseHabit, method: http.MethodPost,
+			target: "/habits/id/pause", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "pause habit with stale etag", handler: (*habit.Api).PauseHabit, method: http.MethodPost,
+			target: "/habits/id/pause", headers: ifMatch(`"2"`), params: habitParam,
+			status: http.StatusPreconditionFailed, problem: e.HabitModified},
+		{name: "pause habit while store is down", option: storeDown, handler: (*habit.Api).PauseHabit,
+			method: http.MethodPost, target: "/habits/id/pause", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+		{name: "pause habit while database is down", option: databaseDown, handler: (*habit.Api).PauseHabit,
+			method: http.MethodPost, target: "/habits/id/pause", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+		{name: "archive habit", handler: (*habit.Api).ArchiveHabit, method: http.MethodPost,
+			target: "/habits/id/archive", params: habitParam, status: http.StatusOK},
+		{name: "activate active habit", handler: (*habit.Api).ActivateHabit, method: http.MethodPost,
+			target: "/habits/id/activate", params: habitParam, status: http.StatusConflict,
+			problem: e.InvalidTransition},
+
+		{name: "level up habit without levels", handler: (*habit.Api).LevelUp, method: http.MethodPost,
+			target: "/habits/id/level-up", params: habitParam, status: http.StatusConflict, problem: e.LevelOutOfRange},
+		{name: "level down missing habit", handler: (*habit.Api).LevelDown, method: http.MethodPost,
+			target: "/habits/id/level-down", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "get levels", handler: (*habit.Api).GetLevels, method: http.MethodGet, target: "/habits/id/levels",
+			params: habitParam, status: http.StatusOK},
+		{name: "get levels with unsupported schedule", handler: (*habit.Api).GetLevels, method: http.MethodGet,
+			target: "/habits/id/levels", params: brokenParam, status: http.StatusUnprocessableEntity,
+			problem: e.UnsupportedSchedule},
+		{name: "get levels while database is down", option: databaseDown, handler: (*habit.Api).GetLevels,
+			method: http.MethodGet, target: "/habits/id/levels", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+
+		{name: "stack habit on itself", handler: (*habit.Api).PatchHabit, method: http.MethodPatch,
+			target: "/habits/id", contentType: habit.ContentTypeMergePatch, params: habitParam,
+			body:   func(f fixture) string { return `{"anchorId":"` + f.habitID.String() + `"}` },
+			status: http.StatusConflict, problem: e.StackCycle},
+		{name: "get stack", handler: (*habit.Api).GetStack, method: http.MethodGet, target: "/habits/id/stack",
+			params: habitParam, status: http.StatusOK},
+		{name: "get stack with invalid id", handler: (*habit.Api).GetStack, method: http.MethodGet,
+			target: "/habits/id/stack", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "get stack of missing habit", handler: (*habit.Api).GetStack, method: http.MethodGet,
+			target: "/habits/id/stack", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "get stack while store is down", option: storeDown, handler: (*habit.Api).GetStack,
+			method: http.MethodGet, target: "/habits/id/stack", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+		{name: "get routine", handler: (*habit.Api).GetRoutine, method: http.MethodGet, target: "/habits/routine",
+			status: http.StatusOK},
+		{name: "get routine while store is down", option: storeDown, handler: (*habit.Api).GetRoutine,
+			method: http.MethodGet, target: "/habits/routine", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+		{name: "get habits at risk while store is down", option: storeDown, handler: (*habit.Api).GetAtRisk,
+			method: http.MethodGet, target: "/habits/at-risk", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+
+		{name: "get streak", handler: (*habit.Api).GetStreak, method: http.MethodGet, target: "/habits/id/streak",
+			params: habitParam, status: http.StatusOK},
+		{name: "get streak with invalid id", handler: (*habit.Api).GetStreak, method: http.MethodGet,
+			target: "/habits/id/streak", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "get streak of missing habit", handler: (*habit.Api).GetStreak, method: http.MethodGet,
+			target: "/habits/id/streak", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "get streak with unsupported schedule", handler: (*habit.Api).GetStreak, method: http.MethodGet,
+			target: "/habits/id/streak", params: brokenParam, status: http.StatusUnprocessableEntity,
+			problem: e.UnsupportedSchedule},
+		{name: "get streak while database is down", option: databaseDown, handler: (*habit.Api).GetStreak,
+			method: http.MethodGet, target: "/habits/id/streak", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+
+		{name: "get completions", handler: (*habit.Api).GetCompletions, method: http.MethodGet,
+			target: "/habits/id/completions?from=2025-01-01", params: habitParam, status: http.StatusOK},
+		{name: "get completions with invalid range", handler: (*habit.Api).GetCompletions, method: http.MethodGet,
+			target: "/habits/id/completions?from=2025-02-01&to=2025-01-01", params: habitParam,
+			status: http.StatusBadRequest, problem: e.InvalidQueryParams},
+		{name: "get completions of missing habit", handler: (*habit.Api).GetCompletions, method: http.MethodGet,
+			target: "/habits/id/completions", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.HabitNotFound},
+		{name: "get completions while database is down", option: databaseDown, handler: (*habit.Api).GetCompletions,
+			method: http.MethodGet, target: "/habits/id/completions", params: habitParam,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+
+		{name: "create completion", handler: (*habit.Api).CreateCompletion, method: http.MethodPost,
+			target: "/habits/id/completions", params: habitParam, body: text(`{"note":"Done"}`),
+			status: http.StatusCreated},
+		{name: "create completion with malformed body", handler: (*habit.Api).CreateCompletion,
+			method: http.MethodPost, target: "/habits/id/completions", params: habitParam, body: text(`{`),
+			status: http.StatusBadRequest, problem: e.JsonDecodeFailure},
+		{name: "create completion of missing habit", handler: (*habit.Api).CreateCompletion, method: http.MethodPost,
+			target: "/habits/id/completions", params: param("id", uuid.NewString()), body: text(`{}`),
+			status: http.StatusNotFound, problem: e.HabitNotFound},
+		{name: "create invalid completion", handler: (*habit.Api).CreateCompletion, method: http.MethodPost,
+			target: "/habits/id/completions", params: habitParam,
+			body: text(`{"note":"` + strings.Repeat("a", 501) + `"}`), status: http.StatusUnprocessableEntity,
+			problem: e.ValidationFailure},
+		{name: "create completion while database is down", option: databaseDown,
+			handler: (*habit.Api).CreateCompletion, method: http.MethodPost, target: "/habits/id/completions",
+			params: habitParam, body: text(`{}`), status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+
+		{name: "delete completion", handler: (*habit.Api).DeleteCompletion, method: http.MethodDelete,
+			target: "/habits/id/completions/completionId", params: completionParams, status: http.StatusOK},
+		{name: "delete completion with invalid id", handler: (*habit.Api).DeleteCompletion, method: http.MethodDelete,
+			target: "/habits/id/completions/completionId", params: param("id", "not-a-uuid"),
+			status: http.StatusBadRequest, problem: e.InvalidUrlRequest},
+		{name: "delete missing completion", handler: (*habit.Api).DeleteCompletion, method: http.MethodDelete,
+			target: "/habits/id/completions/completionId", params: func(f fixture) map[string]string {
+				return map[string]string{"id": f.habitID.String(), "completionId": uuid.NewString()}
+			}, status: http.StatusNotFound, problem: e.CompletionNotFound},
+		{name: "delete completion while database is down", option: databaseDown,
+			handler: (*habit.Api).DeleteCompletion, method: http.MethodDelete,
+			target: "/habits/id/completions/completionId", params: completionParams,
+			status: http.StatusInternalServerError, problem: e.DatabaseConnectionFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(testing *testing.T) {
+			f := newFixture(testing, test.option)
+
+			var body string
+			if test.body != nil {
+				body = test.body(f)
+			}
+			var params map[string]string
+			if test.params != nil {
+				params = test.params(f)
+			}
+
+			request := util.NewRequest(test.method, test.target, body, params, f.userID)
+			if test.contentType != "" {
+				request.Header.Set("Content-Type", test.contentType)
+			}
+			for key, value := range test.headers {
+				request.Header.Set(key, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			test.handler(f.api, recorder, request)
+
+			util.IsEqual(testing, recorder.Code, test.status)
+			if test.problem.Type == "" {
+				return
+			}
+
+			util.IsEqual(testing, recorder.Header().Get("Content-Type"), e.ContentType)
+			problem := e.Problem{}
+			util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&problem))
+			util.IsEqual(testing, problem.Type, test.problem.Type)
+			util.IsEqual(testing, problem.Status, test.status)
+		})
+	}
+}
+
+func TestApi_OtherUsersHabit(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	request := util.NewRequest(http.MethodGet, "/habits/id", "", habitParam(f), uuid.New())
+	f.api.GetHabit(recorder, request)
+
+	util.IsEqual(testing, recorder.Code, http.StatusNotFound)
+}
+
+func TestApi_GetHabitsResponse(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits?limit=1", "", nil, f.userID))
+
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+	util.IsEqual(testing, recorder.Header().Get("Content-Type"), "application/json")
+
+	page := habit.JsonHabits{}
+	decoder := json.NewDecoder(recorder.Body)
+	util.NoError(testing, decoder.Decode(&page))
+	util.IsEqual(testing, len(page.Habits), 1)
+	util.IsEqual(testing, page.NextCursor != nil, true)
+	// The page is the whole body, nothing is written after it.
+	util.IsEqual(testing, decoder.More(), false)
+}
+
+func TestApi_PatchHabit(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	request := util.NewRequest(http.MethodPatch, "/habits/id",
+		`{"colourHex":"#ffffff","schedule":{"type":"times_per_week","times":3}}`, habitParam(f), f.userID)
+	request.Header.Set("Content-Type", habit.ContentTypeMergePatch+"; charset=utf-8")
+
+	recorder := httptest.NewRecorder()
+	f.api.PatchHabit(recorder, request)
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	patched := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&patched))
+	util.IsEqual(testing, patched.Description, "Read")
+	util.IsEqual(testing, patched.ColourHex, "#ffffff")
+	util.IsEqual(testing, patched.IconID, f.iconID)
+	util.IsEqual(testing, patched.Schedule.Type, habit.ScheduleTimesPerWeek)
+	util.IsEqual(testing, patched.Schedule.Times, 3)
+	util.IsEqual(testing, patched.Status, habit.StatusActive)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", habitParam(f), f.userID))
+
+	stored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&stored))
+	util.IsEqual(testing, stored.ColourHex, "#ffffff")
+	util.IsEqual(testing, stored.ModeType, "")
+}
+
+func TestApi_ModeTypeFilter(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Plan the year"), `"schedule":{"type":"daily"}`, `"modeType":"yearly"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	yearlyID := recorder.Header().Get(headers.CREATED_ID)
+
+	for _, modeType := range []string{"yearly", "YEARLY", "every_n_days"} {
+		recorder = httptest.NewRecorder()
+		f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits?modeType="+modeType, "", nil, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		page := habit.JsonHabits{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+		util.IsEqual(testing, len(page.Habits), 1)
+		util.IsEqual(testing, page.Habits[0].ID, yearlyID)
+	}
+}
+
+func TestApi_ScheduleRoundTrip(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	for _, schedule := range []string{
+		`{"type":"daily"}`,
+		`{"type":"times_per_week","times":1}`,
+		`{"type":"times_per_week","times":3}`,
+		`{"type":"weekdays","weekdays":["monday","friday"]}`,
+		`{"type":"every_n_days","interval":3}`,
+		`{"type":"every_n_days","interval":365}`,
+		`{"type":"monthly","dayOfMonth":1}`,
+		`{"type":"monthly","dayOfMonth":15}`,
+	} {
+		recorder := httptest.NewRecorder()
+		body := strings.Replace(f.habitBody("Read"), `{"type":"daily"}`, schedule, 1)
+		f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusCreated)
+		params := param("id", recorder.Header().Get(headers.CREATED_ID))(f)
+
+		recorder = httptest.NewRecorder()
+		f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", params, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+		fetched := recorder.Body.String()
+
+		// The habit is sent back exactly as it was fetched.
+		request := util.NewRequest(http.MethodPut, "/habits/id", fetched, params, f.userID)
+		request.Header.Set("If-Match", recorder.Header().Get("ETag"))
+		recorder = httptest.NewRecorder()
+		f.api.UpdateHabit(recorder, request)
+		if recorder.Code != http.StatusOK {
+			testing.Fatalf("Putting back %s failed with %d: %s", fetched, recorder.Code, recorder.Body.String())
+		}
+
+		recorder = httptest.NewRecorder()
+		f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", params, f.userID))
+		util.IsEqual(testing, recorder.Body.String(), fetched)
+	}
+}
+
+func TestApi_ConcurrentUpdates(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", habitParam(f), f.userID))
+	etag := recorder.Header().Get("ETag")
+	util.IsEqual(testing, etag, `"1"`)
+
+	update := func(description string) *httptest.ResponseRecorder {
+		request := util.NewRequest(http.MethodPut, "/habits/id", f.habitBody(description), habitParam(f), f.userID)
+		request.Header.Set("If-Match", etag)
+
+		recorder := httptest.NewRecorder()
+		f.api.UpdateHabit(recorder, request)
+		return recorder
+	}
+
+	// Both devices read version 1, the second write is rejected instead of overwriting the first.
+	recorder = update("First")
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+	util.IsEqual(testing, recorder.Header().Get("ETag"), `"2"`)
+
+	recorder = update("Second")
+	util.IsEqual(testing, recorder.Code, http.StatusPreconditionFailed)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", habitParam(f), f.userID))
+
+	stored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&stored))
+	util.IsEqual(testing, stored.Description, "First")
+	util.IsEqual(testing, recorder.Header().Get("ETag"), `"2"`)
+}
+
+func TestApi_RestoreHabit(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	serve := func(handler func(*habit.Api, http.ResponseWriter, *http.Request), method string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler(f.api, recorder, util.NewRequest(method, "/habits/id", "", habitParam(f), f.userID))
+		return recorder
+	}
+
+	util.IsEqual(testing, serve((*habit.Api).DeleteHabit, http.MethodDelete).Code, http.StatusOK)
+	util.IsEqual(testing, serve((*habit.Api).GetHabit, http.MethodGet).Code, http.StatusNotFound)
+	util.IsEqual(testing, serve((*habit.Api).GetCompletions, http.MethodGet).Code, http.StatusNotFound)
+
+	recorder := serve((*habit.Api).GetTrash, http.MethodGet)
+	trash := habit.JsonHabits{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&trash))
+	util.IsEqual(testing, len(trash.Habits), 1)
+	util.IsEqual(testing, trash.Habits[0].ID, f.habitID.String())
+	util.IsEqual(testing, trash.Habits[0].DeletedAt != nil, true)
+
+	recorder = serve((*habit.Api).RestoreHabit, http.MethodPost)
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+	util.IsEqual(testing, recorder.Header().Get("ETag"), `"2"`)
+
+	restored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&restored))
+	util.IsEqual(testing, restored.Description, "Read")
+	util.IsEqual(testing, restored.DeletedAt == nil, true)
+
+	// The completions were kept while the habit was in the trash.
+	recorder = serve((*habit.Api).GetCompletions, http.MethodGet)
+	completions := make([]habit.JsonCompletion, 0)
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&completions))
+	util.IsEqual(testing, len(completions), 1)
+}
+
+func TestApi_Lifecycle(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	transition := func(handler func(*habit.Api, http.ResponseWriter, *http.Request)) habit.JsonHabit {
+		recorder := httptest.NewRecorder()
+		handler(f.api, recorder, util.NewRequest(http.MethodPost, "/habits/id", "", habitParam(f), f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		result := habit.JsonHabit{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+		return result
+	}
+	list := func(query string) []habit.JsonHabit {
+		recorder := httptest.NewRecorder()
+		f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits"+query, "", nil, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		page := habit.JsonHabits{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+		return page.Habits
+	}
+
+	util.IsEqual(testing, transition((*habit.Api).PauseHabit).Status, habit.StatusPaused)
+	util.IsEqual(testing, len(list("")), 1)
+	util.IsEqual(testing, list("?status=paused")[0].ID, f.habitID.String())
+
+	util.IsEqual(testing, transition((*habit.Api).ArchiveHabit).Status, habit.StatusArchived)
+	util.IsEqual(testing, len(list("?status=paused")), 0)
+	util.IsEqual(testing, list("?status=archived")[0].ID, f.habitID.String())
+	util.IsEqual(testing, len(list("?status=all")), 2)
+
+	recorder := httptest.NewRecorder()
+	f.api.PauseHabit(recorder, util.NewRequest(http.MethodPost, "/habits/id", "", habitParam(f), f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusConflict)
+
+	util.IsEqual(testing, transition((*habit.Api).ActivateHabit).Status, habit.StatusActive)
+	util.IsEqual(testing, len(list("")), 2)
+
+	// Pausing and archiving kept a single pause, which ended on activation.
+	pauses, err := habit.NewPauseRepository(f.database).GetPauses(f.habitID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(pauses), 1)
+	util.IsEqual(testing, pauses[0].EndedAt != nil, true)
+}
+
+func TestApi_LifecycleRollback(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+	util.NoError(testing, f.database.Exec("DROP TABLE habit_pauses").Error)
+
+	recorder := httptest.NewRecorder()
+	f.api.PauseHabit(recorder, util.NewRequest(http.MethodPost, "/habits/id", "", habitParam(f), f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusInternalServerError)
+
+	// The pause could not be recorded, so the habit was not paused either.
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", habitParam(f), f.userID))
+
+	stored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&stored))
+	util.IsEqual(testing, stored.Status, habit.StatusActive)
+}
+
+func TestApi_Progress(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	patch := func(body string) {
+		request := util.NewRequest(http.MethodPatch, "/habits/id", body, habitParam(f), f.userID)
+		request.Header.Set("Content-Type", habit.ContentTypeMergePatch)
+
+		recorder := httptest.NewRecorder()
+		f.api.PatchHabit(recorder, request)
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+	}
+	get := func() habit.JsonHabit {
+		recorder := httptest.NewRecorder()
+		f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=streak,progress", "",
+			habitParam(f), f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		result := habit.JsonHabit{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+		return result
+	}
+
+	patch(`{"target":{"value":8,"unit":"glasses","aggregation":"sum"}}`)
+
+	recorder := httptest.NewRecorder()
+	f.api.CreateCompletion(recorder, util.NewRequest(http.MethodPost, "/habits/id/completions", `{"amount":3}`,
+		habitParam(f), f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+
+	measured := get()
+	util.IsEqual(testing, *measured.Target, habit.JsonTarget{Value: 8, Unit: "glasses", Aggregation: habit.AggregationSum})
+	util.IsEqual(testing, measured.Progress.Value, 3.0)
+	util.IsEqual(testing, measured.Progress.Goal, 8.0)
+	util.IsEqual(testing, measured.Progress.Done, false)
+	util.IsEqual(testing, measured.Streak.Current, 0)
+
+	patch(`{"target":null}`)
+
+	yesNo := get()
+	util.IsEqual(testing, yesNo.Target == nil, true)
+	util.IsEqual(testing, yesNo.Progress.Value, 2.0)
+	util.IsEqual(testing, yesNo.Progress.Done, true)
+	util.IsEqual(testing, yesNo.Streak.Current, 1)
+}
+
+func TestApi_AvoidedHabit(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Smoke"), `"schedule"`, `"kind":"avoid","schedule"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	params := param("id", recorder.Header().Get(headers.CREATED_ID))(f)
+
+	recorder = httptest.NewRecorder()
+	slippedAt := time.Now().UTC().AddDate(0, 0, -1).Format(time.RFC3339)
+	f.api.CreateCompletion(recorder, util.NewRequest(http.MethodPost, "/habits/id/completions",
+		`{"completedAt":"`+slippedAt+`"}`, params, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=streak,progress", "", params, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	avoided := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&avoided))
+	util.IsEqual(testing, avoided.Kind, habit.KindAvoid)
+	util.IsEqual(testing, avoided.Progress == nil, true)
+	util.IsEqual(testing, avoided.Streak.Current, 0)
+	util.IsEqual(testing, avoided.Streak.Slips.Count, 1)
+	util.IsEqual(testing, avoided.Streak.Slips.PerWeek, 3.5)
+}
+
+func TestApi_FilesystemIcons(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	userID := util.NewUser(testing, database)
+	iconStore := icon.NewLocalStore(testing.TempDir(), database)
+	api := habit.New(habit.NewRepository(database), validation.New(), iconStore, iconMaxBytes)
+
+	recorder := httptest.NewRecorder()
+	icon.New(iconStore, iconMaxBytes).UploadIcon(recorder,
+		util.NewRequest(http.MethodPost, "/icons", testIcon, nil, userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	iconID := recorder.Header().Get(headers.CREATED_ID)
+
+	body := `{"description":"Read","colourHex":"#ffffff","iconId":"` + iconID + `","schedule":{"type":"daily"}}`
+	recorder = httptest.NewRecorder()
+	api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	params := map[string]string{"id": recorder.Header().Get(headers.CREATED_ID)}
+
+	recorder = httptest.NewRecorder()
+	api.UpdateHabit(recorder, util.NewRequest(http.MethodPut, "/habits/id",
+		strings.Replace(body, "Read", "Read a book", 1), params, userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	recorder = httptest.NewRecorder()
+	api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", params, userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	stored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&stored))
+	util.IsEqual(testing, stored.Description, "Read a book")
+	util.IsEqual(testing, stored.IconID, iconID)
+}
+
+func TestApi_Reminder(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Read"), `"schedule"`, `"intention":{"time":"07:30"},"schedule"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	params := param("id", recorder.Header().Get(headers.CREATED_ID))(f)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=reminder", "", params, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	intended := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&intended))
+	reminder := intended.NextReminder.UTC()
+	util.IsEqual(testing, reminder.Hour(), 7)
+	util.IsEqual(testing, reminder.Minute(), 30)
+	util.IsEqual(testing, reminder.After(time.Now()), true)
+	util.IsEqual(testing, reminder.Before(time.Now().Add(24*time.Hour)), true)
+
+	// The time of day is in the time zone of the user.
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	util.NoError(testing, err)
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=reminder&tz=Asia/Tokyo", "", params,
+		f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	intended = habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&intended))
+	reminder = intended.NextReminder.In(tokyo)
+	util.IsEqual(testing, reminder.Hour(), 7)
+	util.IsEqual(testing, reminder.Minute(), 30)
+	util.IsEqual(testing, reminder.After(time.Now()), true)
+	util.IsEqual(testing, reminder.Before(time.Now().Add(24*time.Hour)), true)
+
+	for _, tz := range []string{"Mars/Olympus_Mons", "Local"} {
+		recorder = httptest.NewRecorder()
+		f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=reminder&tz="+tz, "", params,
+			f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusBadRequest)
+	}
+
+	// A habit without a time of day has no reminder.
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=reminder", "", habitParam(f),
+		f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	result := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+	util.IsEqual(testing, result.NextReminder == nil, true)
+}
+
+func TestApi_Risk(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits", "", nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	page := habit.JsonHabits{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+	for _, listed := range page.Habits {
+		// The risk of a habit with a schedule which is not supported is unknown.
+		if listed.ID == f.brokenID.String() {
+			util.IsEqual(testing, listed.Risk == nil, true)
+		} else {
+			util.IsEqual(testing, listed.Risk.Status, habit.RiskOnTrack)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id?include=risk", "", habitParam(f), f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	result := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+	util.IsEqual(testing, result.Risk.Status, habit.RiskOnTrack)
+
+	// Write was done two days ago and missed yesterday, it is the only habit at risk.
+	now := time.Now()
+	missed := &habit.Habit{ID: uuid.New(), UserID: f.userID, Description: "Write", IconID: f.iconID,
+		Schedule: habit.Schedule{Type: habit.ScheduleDaily}, CreatedAt: now.AddDate(0, 0, -7)}
+	_, err := habit.NewRepository(f.database).CreateHabit(missed)
+	util.NoError(testing, err)
+	_, err = habit.NewCompletionRepository(f.database).CreateCompletion(&habit.Completion{ID: uuid.New(),
+		HabitID: missed.ID, CompletedAt: now.AddDate(0, 0, -2)})
+	util.NoError(testing, err)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetAtRisk(recorder, util.NewRequest(http.MethodGet, "/habits/at-risk", "", nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	page = habit.JsonHabits{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+	util.IsEqual(testing, len(page.Habits), 1)
+	util.IsEqual(testing, page.Habits[0].ID, missed.ID.String())
+	util.IsEqual(testing, page.Habits[0].Risk.Status, habit.RiskAtRisk)
+}
+
+func TestApi_Stack(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Write"), `"schedule"`, `"anchorId":"`+f.habitID.String()+`","schedule"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	writeID := recorder.Header().Get(headers.CREATED_ID)
+
+	// Stacking the anchor on the habit stacked on it would make both follow each other.
+	request := util.NewRequest(http.MethodPatch, "/habits/id", `{"anchorId":"`+writeID+`"}`, habitParam(f), f.userID)
+	request.Header.Set("Content-Type", habit.ContentTypeMergePatch)
+	recorder = httptest.NewRecorder()
+	f.api.PatchHabit(recorder, request)
+	util.IsEqual(testing, recorder.Code, http.StatusConflict)
+
+	list := func(handler func(*habit.Api, http.ResponseWriter, *http.Request), params map[string]string) []string {
+		recorder := httptest.NewRecorder()
+		handler(f.api, recorder, util.NewRequest(http.MethodGet, "/habits", "", params, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		page := habit.JsonHabits{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+		ids := make([]string, len(page.Habits))
+		for i, jsonHabit := range page.Habits {
+			ids[i] = jsonHabit.ID
+		}
+		return ids
+	}
+
+	stack := list((*habit.Api).GetStack, param("id", writeID)(f))
+	util.IsEqual(testing, len(stack), 2)
+	util.IsEqual(testing, stack[0], f.habitID.String())
+	util.IsEqual(testing, stack[1], writeID)
+
+	routine := list((*habit.Api).GetRoutine, nil)
+	util.IsEqual(testing, len(routine), 3)
+	util.IsEqual(testing, routine[0], f.habitID.String())
+	util.IsEqual(testing, routine[1], writeID)
+	util.IsEqual(testing, routine[2], f.brokenID.String())
+}
+
+func TestApi_Levels(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Run"), `"schedule"`,
+		`"twoMinuteVersion":"Put on my shoes","levels":["Run 1 km","Run 3 km","Run 5 km"],"schedule"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	params := param("id", recorder.Header().Get(headers.CREATED_ID))(f)
+
+	change := func(handler func(*habit.Api, http.ResponseWriter, *http.Request), status int) habit.JsonHabit {
+		recorder := httptest.NewRecorder()
+		handler(f.api, recorder, util.NewRequest(http.MethodPost, "/habits/id/level", "", params, f.userID))
+		util.IsEqual(testing, recorder.Code, status)
+
+		result := habit.JsonHabit{}
+		if status == http.StatusOK {
+			util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+		}
+		return result
+	}
+
+	change((*habit.Api).LevelDown, http.StatusConflict)
+	util.IsEqual(testing, change((*habit.Api).LevelUp, http.StatusOK).Level, 2)
+	util.IsEqual(testing, change((*habit.Api).LevelUp, http.StatusOK).Level, 3)
+	change((*habit.Api).LevelUp, http.StatusConflict)
+
+	// Dropping the hardest level moves the habit to the hardest level left, which is recorded as a change.
+	request := util.NewRequest(http.MethodPatch, "/habits/id", `{"levels":["Run 1 km","Run 3 km"]}`, params, f.userID)
+	request.Header.Set("Content-Type", habit.ContentTypeMergePatch)
+	recorder = httptest.NewRecorder()
+	f.api.PatchHabit(recorder, request)
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	patched := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&patched))
+	util.IsEqual(testing, patched.TwoMinute, "Put on my shoes")
+	util.IsEqual(testing, patched.Level, 2)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetLevels(recorder, util.NewRequest(http.MethodGet, "/habits/id/levels", "", params, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	levels := habit.JsonLevels{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&levels))
+	util.IsEqual(testing, levels.Level, 2)
+	util.IsEqual(testing, len(levels.Levels), 2)
+	util.IsEqual(testing, len(levels.Changes), 3)
+	util.IsEqual(testing, levels.Changes[1], habit.JsonLevelChange{From: 2, To: 3, ChangedAt: levels.Changes[1].ChangedAt})
+	util.IsEqual(testing, levels.Changes[2], habit.JsonLevelChange{From: 3, To: 2, ChangedAt: levels.Changes[2].ChangedAt})
+}
+
+func TestApi_LevelRollback(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	recorder := httptest.NewRecorder()
+	body := strings.Replace(f.habitBody("Run"), `"schedule"`, `"levels":["Run 1 km","Run 3 km"],"schedule"`, 1)
+	f.api.CreateHabit(recorder, util.NewRequest(http.MethodPost, "/habits", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	params := param("id", recorder.Header().Get(headers.CREATED_ID))(f)
+	util.NoError(testing, f.database.Exec("DROP TABLE habit_level_changes").Error)
+
+	recorder = httptest.NewRecorder()
+	f.api.LevelUp(recorder, util.NewRequest(http.MethodPost, "/habits/id/level-up", "", params, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusInternalServerError)
+
+	// The level change could not be recorded, so the habit stayed at its level.
+	recorder = httptest.NewRecorder()
+	f.api.GetHabit(recorder, util.NewRequest(http.MethodGet, "/habits/id", "", params, f.userID))
+
+	stored := habit.JsonHabit{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&stored))
+	util.IsEqual(testing, stored.Level, 1)
+}
+
+func TestApi_Batch(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	body := `{"operations":[
+		{"op":"create","habit":` + f.habitBody("Write") + `},
+		{"op":"create","habit":` + f.habitBody("") + `},
+		{"op":"update","id":"` + f.habitID.String() + `","version":1,"habit":` + f.habitBody("Read more") + `},
+		{"op":"delete","id":"` + f.brokenID.String() + `","version":2},
+		{"op":"delete","id":"` + uuid.NewString() + `"},
+		{"op":"rename","id":"` + f.brokenID.String() + `"}
+	]}`
+	recorder := httptest.NewRecorder()
+	f.api.Batch(recorder, util.NewRequest(http.MethodPost, "/habits:batch", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	batch := habit.JsonBatchResults{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&batch))
+	util.IsEqual(testing, batch.Committed, true)
+	util.IsEqual(testing, len(batch.Results), 6)
+
+	statuses := []int{http.StatusCreated, http.StatusUnprocessableEntity, http.StatusOK, http.StatusPreconditionFailed,
+		http.StatusNotFound, http.StatusUnprocessableEntity}
+	for i, status := range statuses {
+		util.IsEqual(testing, batch.Results[i].Status, status)
+		util.IsEqual(testing, batch.Results[i].Problem == nil, status < http.StatusBadRequest)
+	}
+	util.IsEqual(testing, batch.Results[0].Habit.Description, "Write")
+	util.IsEqual(testing, batch.Results[1].Problem.Errors[0].Field, "habit.description")
+	util.IsEqual(testing, batch.Results[2].ETag, `"2"`)
+	util.IsEqual(testing, batch.Results[3].Problem.Type, e.HabitModified.Type)
+	util.IsEqual(testing, batch.Results[5].Problem.Errors[0].Field, "op")
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits?sort=description", "", nil, f.userID))
+	page := habit.JsonHabits{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+	util.IsEqual(testing, len(page.Habits), 3)
+	util.IsEqual(testing, page.Habits[1].Description, "Read more")
+	util.IsEqual(testing, page.Habits[2].Description, "Write")
+}
+
+func TestApi_AtomicBatch(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, 0)
+
+	body := `{"atomic":true,"operations":[
+		{"op":"create","habit":` + f.habitBody("Write") + `},
+		{"op":"delete","id":"` + f.habitID.String() + `"},
+		{"op":"delete","id":"` + uuid.NewString() + `"},
+		{"op":"delete","id":"` + f.brokenID.String() + `"}
+	]}`
+	recorder := httptest.NewRecorder()
+	f.api.Batch(recorder, util.NewRequest(http.MethodPost, "/habits:batch", body, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusNotFound)
+
+	batch := habit.JsonBatchResults{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&batch))
+	util.IsEqual(testing, batch.Committed, false)
+
+	problems := []e.Problem{e.BatchRolledBack, e.BatchRolledBack, e.HabitNotFound, e.BatchRolledBack}
+	for i, problem := range problems {
+		util.IsEqual(testing, batch.Results[i].Problem.Type, problem.Type)
+	}
+	util.IsEqual(testing, batch.Results[0].Status, http.StatusFailedDependency)
+	util.IsEqual(testing, batch.Results[0].ID, "")
+
+	recorder = httptest.NewRecorder()
+	f.api.GetHabits(recorder, util.NewRequest(http.MethodGet, "/habits", "", nil, f.userID))
+	page := habit.JsonHabits{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&page))
+	util.IsEqual(testing, len(page.Habits), 2)
+}
+
+func param(key string, value string) func(fixture) map[string]string {
+	return func(fixture) map[string]string { return map[string]string{key: value} }
+}
+
+func habitParam(f fixture) map[string]string {
+	return map[string]string{"id": f.habitID.String()}
+}
+
+func brokenParam(f fixture) map[string]string {
+	return map[string]string{"id": f.brokenID.String()}
+}
+
+func completionParams(f fixture) map[string]string {
+	return map[string]string{"id": f.habitID.String(), "completionId": f.completionID.String()}
+}
+
+func ifMatch(etag string) map[string]string {
+	return map[string]string{"If-Match": etag}
+}
+
+func ifNoneMatch(etag string) map[string]string {
+	return map[string]string{"If-None-Match": etag}
+}
+
+func text(body string) func(fixture) string {
+	return func(fixture) string { return body }
+}
@@ -0,0 +1,77 @@
+package habit
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+)
+
+func patchableHabit() *habit.Habit {
+	return &habit.Habit{
+		ID:          uuid.New(),
+		Kind:        habit.KindBuild,
+		Description: "Read",
+		ColourHex:   "#000000",
+		IconID:      "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+		Schedule:    habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: 0b0000110},
+	}
+}
+
+func TestApplyPatch_MergePatch(testing *testing.T) {
+	testing.Parallel()
+
+	original := patchableHabit()
+
+	patched, err := habit.ApplyPatch(original, habit.ContentTypeMergePatch,
+		[]byte(`{"description":"Read more","schedule":{"weekdays":["saturday"]}}`))
+	util.NoError(testing, err)
+
+	result := patched.ToHabit()
+	util.IsEqual(testing, result.Description, "Read more")
+	util.IsEqual(testing, result.ColourHex, original.ColourHex)
+	util.IsEqual(testing, result.IconID, original.IconID)
+	util.IsEqual(testing, result.Schedule, habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: 0b1000000})
+	util.IsEqual(testing, len(original.ChangedFields(result)), 2)
+}
+
+func TestApplyPatch_JsonPatch(testing *testing.T) {
+	testing.Parallel()
+
+	original := patchableHabit()
+
+	patched, err := habit.ApplyPatch(original, habit.ContentTypeJsonPatch,
+		[]byte(`[{"op":"replace","path":"/colourHex","value":"#ffffff"}]`))
+	util.NoError(testing, err)
+	util.IsEqual(testing, patched.ColourHex, "#ffffff")
+
+	_, err = habit.ApplyPatch(original, habit.ContentTypeJsonPatch,
+		[]byte(`[{"op":"remove","path":"/missing"}]`))
+	util.IsEqual(testing, errors.Is(err, habit.ErrPatchConflict), true)
+
+	_, err = habit.ApplyPatch(original, habit.ContentTypeJsonPatch, []byte(`{"op":"remove"}`))
+	util.IsEqual(testing, errors.Is(err, habit.ErrInvalidPatch), true)
+}
+
+func TestApplyPatch_LegacyFields(testing *testing.T) {
+	testing.Parallel()
+
+	original := patchableHabit()
+
+	patched, err := habit.ApplyPatch(original, habit.ContentTypeMergePatch,
+		[]byte(`{"modeType":"weekly","iconBase64":"data:image/png;base64,AAAA"}`))
+	util.NoError(testing, err)
+	util.IsEqual(testing, patched.Schedule == nil, true)
+	util.IsEqual(testing, patched.IconID, "")
+	util.IsEqual(testing, patched.ToHabit().Schedule, habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 1})
+}
+
+func TestApplyPatch_UnsupportedContentType(testing *testing.T) {
+	testing.Parallel()
+
+	for _, contentType := range []string{"", "application/json", "text/plain"} {
+		_, err := habit.ApplyPatch(patchableHabit(), contentType, []byte(`{}`))
+		util.IsEqual(testing, errors.Is(err, habit.ErrUnsupportedPatch), true)
+	}
+}
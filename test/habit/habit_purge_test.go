@@ -0,0 +1,67 @@
+package habit
+
+import (
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestPurger_Purge(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	store := habit.NewRepository(database)
+	completions := habit.NewCompletionRepository(database)
+	userID, iconID := util.NewUser(testing, database), util.NewIcon(testing, database)
+	now := time.Now()
+
+	ids := make([]uuid.UUID, 0, 2)
+	for range 2 {
+		newHabit := &habit.Habit{ID: uuid.New(), UserID: userID, IconID: iconID,
+			Schedule: habit.Schedule{Type: habit.ScheduleDaily}}
+		_, err := store.CreateHabit(newHabit)
+		util.NoError(testing, err)
+		_, err = completions.CreateCompletion(&habit.Completion{ID: uuid.New(), HabitID: newHabit.ID, CompletedAt: now})
+		util.NoError(testing, err)
+		_, err = store.Pauses().StartPause(&habit.Pause{ID: uuid.New(), HabitID: newHabit.ID, StartedAt: now})
+		util.NoError(testing, err)
+		_, err = store.Levels().CreateLevelChange(&habit.LevelChange{ID: uuid.New(), HabitID: newHabit.ID,
+			FromLevel: 1, ToLevel: 2, ChangedAt: now})
+		util.NoError(testing, err)
+		ids = append(ids, newHabit.ID)
+	}
+
+	_, err := store.DeleteHabit(userID, ids[0], 0)
+	util.NoError(testing, err)
+
+	purger := habit.NewPurger(store, 24*time.Hour)
+
+	purged, err := purger.Purge(now)
+	util.NoError(testing, err)
+	util.IsEqual(testing, purged, 0)
+
+	purged, err = purger.Purge(now.Add(25 * time.Hour))
+	util.NoError(testing, err)
+	util.IsEqual(testing, purged, 1)
+
+	remaining, err := completions.GetCompletions(ids[0], time.Time{}, time.Time{})
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(remaining), 0)
+
+	remaining, err = completions.GetCompletions(ids[1], time.Time{}, time.Time{})
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(remaining), 1)
+
+	// The pauses and level changes of the purged habit are deleted with it.
+	for i, expected := range []int{0, 1} {
+		pauses, err := store.Pauses().GetPauses(ids[i])
+		util.NoError(testing, err)
+		util.IsEqual(testing, len(pauses), expected)
+
+		changes, err := store.Levels().GetLevelChanges(ids[i])
+		util.NoError(testing, err)
+		util.IsEqual(testing, len(changes), expected)
+	}
+}
@@ -0,0 +1,97 @@
+package habit
+
+import (
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseHabitQuery_Defaults(testing *testing.T) {
+	testing.Parallel()
+
+	query, err := habit.ParseHabitQuery(httptest.NewRequest("GET", "/v1/habits", nil))
+	util.NoError(testing, err)
+
+	util.IsEqual(testing, query.Limit, 20)
+	util.IsEqual(testing, query.Sort, habit.SortCreatedAt)
+	util.IsEqual(testing, query.Descending, false)
+	util.IsEqual(testing, query.After == nil, true)
+	util.IsEqual(testing, query.Status, habit.StatusActive)
+}
+
+func TestParseHabitQuery_Status(testing *testing.T) {
+	testing.Parallel()
+
+	for status, expected := range map[string]string{
+		"active":   habit.StatusActive,
+		"paused":   habit.StatusPaused,
+		"archived": habit.StatusArchived,
+		"all":      "",
+	} {
+		query, err := habit.ParseHabitQuery(httptest.NewRequest("GET", "/v1/habits?status="+status, nil))
+		util.NoError(testing, err)
+		util.IsEqual(testing, query.Status, expected)
+	}
+}
+
+func TestParseHabitQuery_ModeType(testing *testing.T) {
+	testing.Parallel()
+
+	for modeType, expected := range map[string]string{
+		"weekdays": habit.ScheduleWeekdays,
+		"Weekdays": habit.ScheduleWeekdays,
+		"WEEKDAYS": habit.ScheduleWeekdays,
+		"WEEKLY":   habit.ScheduleTimesPerWeek,
+		"yearly":   habit.ScheduleEveryNDays,
+		"Monthly":  habit.ScheduleMonthly,
+	} {
+		query, err := habit.ParseHabitQuery(httptest.NewRequest("GET", "/v1/habits?modeType="+modeType, nil))
+		util.NoError(testing, err)
+		util.IsEqual(testing, query.ScheduleType, expected)
+	}
+}
+
+func TestParseHabitQuery_CursorRoundTrip(testing *testing.T) {
+	testing.Parallel()
+
+	last := &habit.Habit{ID: uuid.New(), Description: "Read", CreatedAt: time.Date(2025, 4, 10, 7, 30, 0, 123000, time.UTC)}
+	cursor := habit.NewCursor(habit.HabitQuery{Sort: habit.SortDescription, Descending: true}, last).Encode()
+
+	query, err := habit.ParseHabitQuery(httptest.NewRequest("GET",
+		"/v1/habits?limit=5&sort=-description&modeType=weekdays&search=+read+&cursor="+cursor, nil))
+	util.NoError(testing, err)
+
+	util.IsEqual(testing, query.Limit, 5)
+	util.IsEqual(testing, query.Sort, habit.SortDescription)
+	util.IsEqual(testing, query.Descending, true)
+	util.IsEqual(testing, query.ScheduleType, habit.ScheduleWeekdays)
+	util.IsEqual(testing, query.Search, "read")
+	util.IsEqual(testing, query.After.ID, last.ID)
+	util.IsEqual(testing, query.After.Description, last.Description)
+	util.IsEqual(testing, query.After.CreatedAt.Equal(last.CreatedAt), true)
+}
+
+func TestParseHabitQuery_Invalid(testing *testing.T) {
+	testing.Parallel()
+
+	createdAtCursor := habit.NewCursor(habit.HabitQuery{Sort: habit.SortCreatedAt}, &habit.Habit{ID: uuid.New()}).Encode()
+
+	for _, url := range []string{
+		"/v1/habits?limit=0",
+		"/v1/habits?limit=101",
+		"/v1/habits?limit=ten",
+		"/v1/habits?sort=colourHex",
+		"/v1/habits?modeType=hourly",
+		"/v1/habits?status=deleted",
+		"/v1/habits?cursor=not-a-cursor",
+		"/v1/habits?sort=description&cursor=" + createdAtCursor,
+		"/v1/habits?sort=-createdAt&cursor=" + createdAtCursor,
+	} {
+		if _, err := habit.ParseHabitQuery(httptest.NewRequest("GET", url, nil)); err == nil {
+			testing.Errorf("Expected %s to be rejected", url)
+		}
+	}
+}
@@ -1,13 +1,29 @@
 package habit
 
 import (
+	"database/sql/driver"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"habitgobackend/cmd/api/resource/habit"
 	"habitgobackend/test/util"
 	"testing"
+	"time"
 )
 
+var habitColumns = []string{"id", "user_id", "kind", "description", "colour_hex", "icon_id", "schedule_type", "schedule_times",
+	"schedule_weekdays", "schedule_interval", "schedule_day_of_month", "target_value", "target_unit", "target_aggregation",
+	"intention_time", "intention_location", "intention_cue", "anchor_id", "two_minute_version", "levels", "level", "status", "version",
+	"created_at", "updated_at", "deleted_at"}
+
+func habitRow(h *habit.Habit) []driver.Value {
+	levels, _ := h.Levels.Value()
+	return []driver.Value{h.ID, h.UserID, h.Kind, h.Description, h.ColourHex, h.IconID, h.Schedule.Type, h.Schedule.Times,
+		h.Schedule.Weekdays, h.Schedule.Interval, h.Schedule.DayOfMonth, h.Target.Value, h.Target.Unit, h.Target.Aggregation,
+		h.Intention.Time, h.Intention.Location, h.Intention.Cue, h.AnchorID, h.TwoMinute, levels, h.Level, h.Status, h.Version,
+		h.CreatedAt, h.UpdatedAt, h.DeletedAt}
+}
+
 func TestRepository_GetHabits(testing *testing.T) {
 	testing.Parallel()
 
@@ -16,41 +32,44 @@ func TestRepository_GetHabits(testing *testing.T) {
 
 	repository := habit.NewRepository(database)
 
+	userID := uuid.New()
 	expectedHabits := habit.Habits{
 		{
 			ID:          uuid.New(),
+			UserID:      userID,
 			Description: "Random habit",
 			ColourHex:   "#000000",
-			IconBase64:  "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+P+ErkJggg==",
-			ModeType:    "daily",
+			IconID:      "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+			Schedule:    habit.Schedule{Type: habit.ScheduleDaily},
 		},
 		{
 			ID:          uuid.New(),
+			UserID:      userID,
 			Description: "Drink water",
 			ColourHex:   "#ffffff",
-			IconBase64:  "data:image/png;base64,iVBORw0KGgoAAk+P+ErkJggg==",
-			ModeType:    "monthly",
+			IconID:      "d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab35",
+			Schedule:    habit.Schedule{Type: habit.ScheduleMonthly, DayOfMonth: 15},
 		},
 		{
 			ID:          uuid.New(),
+			UserID:      userID,
 			Description: "Write some code",
 			ColourHex:   "#bbbbbb",
-			IconBase64:  "data:image/png;base64,iVBORwsdsdasgoAAk+P+ErkJggg==",
-			ModeType:    "daily",
+			IconID:      "4e07408562bedb8b60ce05c1decfe3ad16b72230967de01f640b7e4729b49fce",
+			Schedule:    habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: 0b0101010},
 		},
 	}
 
-	rows := sqlmock.NewRows([]string{"ID", "Description", "ColourHex", "IconBase64", "ModeType"}).
-		AddRow(expectedHabits[0].ID, expectedHabits[0].Description, expectedHabits[0].ColourHex,
-			expectedHabits[0].IconBase64, expectedHabits[0].ModeType).
-		AddRow(expectedHabits[1].ID, expectedHabits[1].Description, expectedHabits[1].ColourHex,
-			expectedHabits[1].IconBase64, expectedHabits[1].ModeType).
-		AddRow(expectedHabits[2].ID, expectedHabits[2].Description, expectedHabits[2].ColourHex,
-			expectedHabits[2].IconBase64, expectedHabits[2].ModeType)
+	rows := sqlmock.NewRows(habitColumns)
+	for _, expectedHabit := range expectedHabits {
+		rows.AddRow(habitRow(expectedHabit)...)
+	}
 
-	mock.ExpectQuery("SELECT (.+) FROM \"habits\"").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT (.+) FROM \"habits\" WHERE user_id = \\$1 AND \"habits\".\"deleted_at\" IS NULL ORDER BY created_at ASC, id ASC LIMIT \\$2").
+		WithArgs(userID, 21).
+		WillReturnRows(rows)
 
-	habits, err := repository.GetHabits()
+	habits, err := repository.GetHabits(userID, habit.HabitQuery{Limit: 20, Sort: habit.SortCreatedAt})
 
 	util.NoError(testing, err)
 
@@ -58,6 +77,38 @@ func TestRepository_GetHabits(testing *testing.T) {
 	util.HabitsEqual(testing, habits, expectedHabits)
 }
 
+func TestRepository_GetHabitsPage(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewRepository(database)
+
+	userID := uuid.New()
+	after := &habit.Cursor{Sort: habit.SortDescription, Descending: true, Description: "Read", ID: uuid.New()}
+
+	mock.ExpectQuery("SELECT (.+) FROM \"habits\" WHERE user_id = \\$1 AND status = \\$2 AND schedule_type = \\$3 "+
+		"AND LOWER\\(description\\) LIKE \\$4 ESCAPE '\\\\' "+
+		"AND \\(description < \\$5 OR \\(description = \\$6 AND id < \\$7\\)\\) "+
+		"AND \"habits\".\"deleted_at\" IS NULL ORDER BY description DESC, id DESC LIMIT \\$8").
+		WithArgs(userID, habit.StatusPaused, habit.ScheduleDaily, "%100\\%%", "Read", "Read", after.ID, 6).
+		WillReturnRows(sqlmock.NewRows(habitColumns))
+
+	habits, err := repository.GetHabits(userID, habit.HabitQuery{
+		Limit:        5,
+		Sort:         habit.SortDescription,
+		Descending:   true,
+		After:        after,
+		Status:       habit.StatusPaused,
+		ScheduleType: habit.ScheduleDaily,
+		Search:       "100%",
+	})
+
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(habits), 0)
+}
+
 func TestRepository_CreateHabit(testing *testing.T) {
 	testing.Parallel()
 
@@ -67,16 +118,19 @@ func TestRepository_CreateHabit(testing *testing.T) {
 	repository := habit.NewRepository(database)
 
 	id := uuid.New()
+	userID := uuid.New()
 
 	mock.ExpectBegin()
 	mock.ExpectExec("^INSERT INTO \"habits\" ").
-		WithArgs(id, "Description", "#000000", "data:image/png;base64,iVBORw0KGgoAAAANSUhE+ErkJggg==", "daily").
+		WithArgs(id, userID, habit.KindBuild, "Description", "#000000", "3fdba35f04dc8c462986c992bcf875546257113072a909c162f7e470e581e278",
+			habit.ScheduleTimesPerWeek, 3, 0, 0, 0, 0.0, "", "", "", "", "", nil, "", "[]", 0, habit.StatusActive, 1,
+			util.AnyTime{}, util.AnyTime{}, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	newHabit := &habit.Habit{ID: id, Description: "Description",
-		IconBase64: "data:image/png;base64,iVBORw0KGgoAAAANSUhE+ErkJggg==",
-		ColourHex:  "#000000", ModeType: "daily"}
+	newHabit := &habit.Habit{ID: id, UserID: userID, Description: "Description",
+		IconID:    "3fdba35f04dc8c462986c992bcf875546257113072a909c162f7e470e581e278",
+		ColourHex: "#000000", Schedule: habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 3}}
 
 	result, err := repository.CreateHabit(newHabit)
 	util.NoError(testing, err)
@@ -92,23 +146,79 @@ func TestRepository_UpdateHabit(testing *testing.T) {
 	repository := habit.NewRepository(database)
 
 	id := uuid.New()
-	_ = sqlmock.NewRows([]string{"id", "description", "colour_hex", "icon_base_64", "mode_type"}).
-		AddRow(id, "Description", "Hex", "Icon", "Mode").
-		AddRow(uuid.New(), "Random Description", "Colour Hex", "Icon base 64", "Mode Type")
+	userID := uuid.New()
+	anchorID := uuid.New()
 
 	mock.ExpectBegin()
 	mock.ExpectExec("^UPDATE \"habits\" SET").
-		WithArgs("Updated Description", "Updated Hex", "Updated Icon", "Updated Mode", id).
+		WithArgs(habit.KindAvoid, "Updated Description", "Updated Hex",
+			"4b227777d4dd1fc61c6f884f48641d02b4d121d3fd328cb08b5531fcacdabf8a",
+			habit.ScheduleEveryNDays, 0, 0, 2, 0, 0.0, "", "", "07:30", "", "", anchorID, "Put on my shoes",
+			`["Run 1 km","Run 5 km"]`, 2, 4, util.AnyTime{}, id, userID, 3).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	newHabit := &habit.Habit{ID: id, Description: "Updated Description",
-		IconBase64: "Updated Icon",
-		ColourHex:  "Updated Hex", ModeType: "Updated Mode"}
+	newHabit := &habit.Habit{ID: id, UserID: userID, Kind: habit.KindAvoid, Description: "Updated Description",
+		IconID:    "4b227777d4dd1fc61c6f884f48641d02b4d121d3fd328cb08b5531fcacdabf8a",
+		ColourHex: "Updated Hex", Schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 2},
+		Intention: habit.Intention{Time: "07:30"}, AnchorID: &anchorID, TwoMinute: "Put on my shoes",
+		Levels: habit.Levels{"Run 1 km", "Run 5 km"}, Level: 2, Version: 3}
 
 	result, err := repository.UpdateHabit(newHabit)
 	util.NoError(testing, err)
 	util.IsEqual(testing, 1, result)
+	util.IsEqual(testing, newHabit.Version, 4)
+}
+
+func TestRepository_UpdateModifiedHabit(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewRepository(database)
+
+	id := uuid.New()
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE \"habits\" SET (.+) WHERE (.+) AND version = \\$[0-9]+").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM \"habits\" WHERE \\(id = \\$1 AND user_id = \\$2\\) "+
+		"AND \"habits\".\"deleted_at\" IS NULL").
+		WithArgs(id, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	modifiedHabit := &habit.Habit{ID: id, UserID: userID, Description: "Stale", Version: 1}
+
+	_, err = repository.UpdateHabit(modifiedHabit)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitModified), true)
+	util.IsEqual(testing, modifiedHabit.Version, 1)
+}
+
+func TestRepository_PatchHabit(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewRepository(database)
+
+	id := uuid.New()
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE \"habits\" SET \"colour_hex\"=\\$1,\"version\"=\\$2,\"updated_at\"=\\$3 WHERE").
+		WithArgs("#ffffff", 2, util.AnyTime{}, id, userID, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	patchedHabit := &habit.Habit{ID: id, UserID: userID, Description: "Not written", ColourHex: "#ffffff", Version: 1}
+
+	result, err := repository.PatchHabit(patchedHabit, []string{habit.FieldColourHex})
+	util.NoError(testing, err)
+	util.IsEqual(testing, result, int64(1))
 }
 
 func TestRepository_GetHabit(testing *testing.T) {
@@ -121,21 +231,20 @@ func TestRepository_GetHabit(testing *testing.T) {
 
 	expectedHabit := habit.Habit{
 		ID:          uuid.New(),
+		UserID:      uuid.New(),
 		Description: "Random habit",
 		ColourHex:   "#000000",
-		IconBase64:  "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+P+ErkJggg==",
-		ModeType:    "daily",
+		IconID:      "ef2d127de37b942baad06145e54b0c619a1f22327b2ebbcfbec78f5564afe39d",
+		Schedule:    habit.Schedule{Type: habit.ScheduleDaily},
 	}
 
-	rows := sqlmock.NewRows([]string{"ID", "Description", "ColourHex", "IconBase64", "ModeType"}).
-		AddRow(expectedHabit.ID, expectedHabit.Description, expectedHabit.ColourHex, expectedHabit.IconBase64,
-			expectedHabit.ModeType)
+	rows := sqlmock.NewRows(habitColumns).AddRow(habitRow(&expectedHabit)...)
 
 	mock.ExpectQuery("SELECT (.+) FROM \"habits\" WHERE (.+)").
-		WithArgs(expectedHabit.ID, 1).
+		WithArgs(expectedHabit.ID, expectedHabit.UserID, 1).
 		WillReturnRows(rows)
 
-	result, err := repository.GetHabit(expectedHabit.ID)
+	result, err := repository.GetHabit(expectedHabit.UserID, expectedHabit.ID)
 
 	util.NoError(testing, err)
 
@@ -152,20 +261,49 @@ func TestRepository_DeleteHabit(testing *testing.T) {
 
 	expectedHabit := habit.Habit{
 		ID:          uuid.New(),
+		UserID:      uuid.New(),
 		Description: "Random habit",
 		ColourHex:   "#000000",
-		IconBase64:  "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+P+ErkJggg==",
-		ModeType:    "daily",
+		IconID:      "e7f6c011776e8db7cd330b54174fd76f7d0216b612387a5ffcfb81e6f0919683",
+		Schedule:    habit.Schedule{Type: habit.ScheduleDaily},
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("DELETE * FROM \"habits\" WHERE (.+)").
-		WithArgs(expectedHabit.ID).
+	mock.ExpectExec("^UPDATE \"habits\" SET \"deleted_at\"=\\$1 WHERE (.+) AND \"habits\".\"deleted_at\" IS NULL").
+		WithArgs(util.AnyTime{}, expectedHabit.ID, expectedHabit.UserID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	result, err := repository.DeleteHabit(expectedHabit.ID)
+	result, err := repository.DeleteHabit(expectedHabit.UserID, expectedHabit.ID, 0)
 
 	util.NoError(testing, err)
 	util.IsEqual(testing, result, 1)
 }
+
+func TestRepository_PurgeHabits(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := habit.NewRepository(database)
+
+	deletedBefore := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT \"id\" FROM \"habits\" WHERE deleted_at < \\$1 FOR UPDATE$").
+		WithArgs(deletedBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectExec("^DELETE FROM \"habits\" WHERE id IN \\(\\$1\\) AND deleted_at < \\$2$").
+		WithArgs(id, deletedBefore).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	purged, err := repository.PurgeHabits(deletedBefore)
+
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(purged), 1)
+	util.IsEqual(testing, purged[0], id)
+	util.NoError(testing, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,326 @@
+package habit
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStore returns a repository on a fresh in-memory database with a user owning the habits of the test,
+// and the icon of the habits.
+func newStore(testing *testing.T) (habit.HabitStore, *gorm.DB, uuid.UUID, string) {
+	database := util.NewMemoryDatabase(testing)
+	return habit.NewRepository(database), database, util.NewUser(testing, database), util.NewIcon(testing, database)
+}
+
+func TestHabitStore_CRUD(testing *testing.T) {
+	store, _, userID, iconID := newStore(testing)
+	otherUserID := uuid.New()
+	newHabit := &habit.Habit{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Description: "Read a book",
+		ColourHex:   "#000000",
+		IconID:      iconID,
+		Schedule:    habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: 0b0101010},
+	}
+
+	_, err := store.CreateHabit(newHabit)
+	util.NoError(testing, err)
+	util.IsEqual(testing, newHabit.CreatedAt.IsZero(), false)
+	util.IsEqual(testing, newHabit.Version, 1)
+
+	retrieved, err := store.GetHabit(userID, newHabit.ID)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, habit.Habits{retrieved}, habit.Habits{newHabit})
+
+	_, err = store.GetHabit(otherUserID, newHabit.ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+
+	update := &habit.Habit{
+		ID:          newHabit.ID,
+		UserID:      otherUserID,
+		Kind:        habit.KindBuild,
+		Description: "Read two books",
+		ColourHex:   "#ffffff",
+		IconID:      iconID,
+		Schedule:    habit.Schedule{Type: habit.ScheduleDaily},
+		Version:     1,
+	}
+	rows, err := store.UpdateHabit(update)
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(0))
+
+	update.UserID = userID
+	rows, err = store.UpdateHabit(update)
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(1))
+	util.IsEqual(testing, update.Version, 2)
+
+	retrieved, err = store.GetHabit(userID, newHabit.ID)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, habit.Habits{retrieved}, habit.Habits{update})
+	util.IsEqual(testing, retrieved.Version, 2)
+
+	stale := *update
+	stale.Version = 1
+	_, err = store.UpdateHabit(&stale)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitModified), true)
+
+	patch := &habit.Habit{ID: newHabit.ID, UserID: userID, Description: "Ignored", ColourHex: "#bbbbbb", Version: 2}
+	rows, err = store.PatchHabit(patch, []string{habit.FieldColourHex})
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(1))
+	util.IsEqual(testing, patch.Version, 3)
+
+	_, err = store.PatchHabit(patch, []string{habit.FieldColourHex})
+	util.NoError(testing, err)
+	_, err = store.PatchHabit(&stale, []string{habit.FieldColourHex})
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitModified), true)
+
+	update.ColourHex = "#bbbbbb"
+	retrieved, err = store.GetHabit(userID, newHabit.ID)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, habit.Habits{retrieved}, habit.Habits{update})
+	util.IsEqual(testing, retrieved.Version, 4)
+
+	rows, err = store.DeleteHabit(otherUserID, newHabit.ID, 0)
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(0))
+
+	_, err = store.DeleteHabit(userID, newHabit.ID, 3)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitModified), true)
+
+	rows, err = store.DeleteHabit(userID, newHabit.ID, 4)
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(1))
+
+	_, err = store.GetHabit(userID, newHabit.ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+
+	rows, err = store.DeleteHabit(userID, newHabit.ID, 4)
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(0))
+}
+
+func TestHabitStore_GetHabits(testing *testing.T) {
+	store, database, userID, iconID := newStore(testing)
+	start := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	descriptions := []string{"Walk", "read 100% of a book", "Meditate", "Read the news", "Stretch"}
+	habits := make(habit.Habits, 0, len(descriptions))
+	for i, description := range descriptions {
+		newHabit := &habit.Habit{
+			ID:          uuid.New(),
+			UserID:      userID,
+			Description: description,
+			ColourHex:   "#000000",
+			IconID:      iconID,
+			Schedule:    habit.Schedule{Type: habit.ScheduleDaily},
+			CreatedAt:   start.Add(time.Duration(i) * time.Hour),
+		}
+		if i%2 == 1 {
+			newHabit.Schedule = habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 3}
+		}
+		_, err := store.CreateHabit(newHabit)
+		util.NoError(testing, err)
+		habits = append(habits, newHabit)
+	}
+
+	_, err := store.CreateHabit(&habit.Habit{ID: uuid.New(), UserID: util.NewUser(testing, database),
+		Description: "Someone else's", IconID: iconID, Schedule: habit.Schedule{Type: habit.ScheduleDaily},
+		CreatedAt: start})
+	util.NoError(testing, err)
+
+	query := habit.HabitQuery{Limit: 2, Sort: habit.SortCreatedAt}
+	page, err := store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habits[:3])
+
+	cursor := habit.NewCursor(query, page[1])
+	query.After = &cursor
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habits[2:5])
+
+	query = habit.HabitQuery{Limit: 10, Sort: habit.SortDescription, Descending: true}
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	// Descriptions are compared byte by byte, so lower case sorts after upper case.
+	util.HabitsEqual(testing, page, habit.Habits{habits[1], habits[0], habits[4], habits[3], habits[2]})
+
+	query = habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt, Search: "READ"}
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{habits[1], habits[3]})
+
+	query = habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt, Search: "100%"}
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{habits[1]})
+
+	query = habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt, ScheduleType: habit.ScheduleTimesPerWeek}
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{habits[1], habits[3]})
+
+	paused := *habits[2]
+	paused.Status = habit.StatusPaused
+	_, err = store.PatchHabit(&paused, []string{habit.FieldStatus})
+	util.NoError(testing, err)
+
+	query = habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt, Status: habit.StatusPaused}
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{habits[2]})
+
+	query.Status = habit.StatusActive
+	page, err = store.GetHabits(userID, query)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{habits[0], habits[1], habits[3], habits[4]})
+}
+
+func TestHabitStore_Trash(testing *testing.T) {
+	store, _, userID, iconID := newStore(testing)
+
+	habits := make(habit.Habits, 0, 3)
+	for _, description := range []string{"Walk", "Read", "Stretch"} {
+		newHabit := &habit.Habit{ID: uuid.New(), UserID: userID, Description: description, IconID: iconID,
+			Schedule: habit.Schedule{Type: habit.ScheduleDaily}}
+		_, err := store.CreateHabit(newHabit)
+		util.NoError(testing, err)
+		habits = append(habits, newHabit)
+	}
+
+	for _, deleted := range habits[:2] {
+		rows, err := store.DeleteHabit(userID, deleted.ID, 0)
+		util.NoError(testing, err)
+		util.IsEqual(testing, rows, int64(1))
+		time.Sleep(time.Millisecond)
+	}
+
+	page, err := store.GetHabits(userID, habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt})
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habits[2:])
+
+	trash, err := store.GetTrash(userID)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, trash, habit.Habits{habits[1], habits[0]})
+	util.IsEqual(testing, trash[0].DeletedAt.Valid, true)
+
+	trash, err = store.GetTrash(uuid.New())
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(trash), 0)
+
+	patch := *habits[0]
+	rows, err := store.PatchHabit(&patch, []string{habit.FieldDescription})
+	util.NoError(testing, err)
+	util.IsEqual(testing, rows, int64(0))
+
+	_, err = store.RestoreHabit(uuid.New(), habits[0].ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+	_, err = store.RestoreHabit(userID, habits[2].ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+
+	restored, err := store.RestoreHabit(userID, habits[0].ID)
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, habit.Habits{restored}, habits[:1])
+	util.IsEqual(testing, restored.Version, 2)
+	util.IsEqual(testing, restored.DeletedAt.Valid, false)
+
+	_, err = store.GetHabit(userID, habits[0].ID)
+	util.NoError(testing, err)
+
+	purged, err := store.PurgeHabits(time.Now().Add(-time.Hour))
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(purged), 0)
+
+	purged, err = store.PurgeHabits(time.Now())
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(purged), 1)
+	util.IsEqual(testing, purged[0], habits[1].ID)
+
+	trash, err = store.GetTrash(userID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, len(trash), 0)
+	_, err = store.RestoreHabit(userID, habits[1].ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+}
+
+func TestHabitStore_Transaction(testing *testing.T) {
+	store, _, userID, iconID := newStore(testing)
+	newHabit := func(description string) *habit.Habit {
+		return &habit.Habit{ID: uuid.New(), UserID: userID, Description: description, ColourHex: "#000000",
+			IconID: iconID, Schedule: habit.Schedule{Type: habit.ScheduleDaily}}
+	}
+	errRollback := errors.New("rollback")
+	kept, discarded, nested := newHabit("Kept"), newHabit("Discarded"), newHabit("Nested")
+
+	err := store.Transaction(func(store habit.HabitStore) error {
+		_, err := store.CreateHabit(kept)
+		util.NoError(testing, err)
+
+		err = store.Transaction(func(store habit.HabitStore) error {
+			_, err := store.CreateHabit(nested)
+			util.NoError(testing, err)
+			return errRollback
+		})
+		util.IsEqual(testing, errors.Is(err, errRollback), true)
+
+		_, err = store.GetHabit(userID, nested.ID)
+		util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+		return nil
+	})
+	util.NoError(testing, err)
+
+	err = store.Transaction(func(store habit.HabitStore) error {
+		_, err := store.CreateHabit(discarded)
+		util.NoError(testing, err)
+		_, err = store.DeleteHabit(userID, kept.ID, 0)
+		util.NoError(testing, err)
+		return errRollback
+	})
+	util.IsEqual(testing, errors.Is(err, errRollback), true)
+
+	page, err := store.GetHabits(userID, habit.HabitQuery{Limit: 10, Sort: habit.SortCreatedAt})
+	util.NoError(testing, err)
+	util.HabitsEqual(testing, page, habit.Habits{kept})
+}
+
+func TestHabitStore_Constraints(testing *testing.T) {
+	store, database, userID, iconID := newStore(testing)
+
+	_, err := store.CreateHabit(&habit.Habit{ID: uuid.New(), UserID: userID, IconID: iconID,
+		Schedule: habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 8}})
+	util.IsEqual(testing, err != nil, true)
+
+	_, err = store.CreateHabit(&habit.Habit{ID: uuid.New(), UserID: userID, IconID: strings.Repeat("0", 64),
+		Schedule: habit.Schedule{Type: habit.ScheduleDaily}})
+	util.IsEqual(testing, err != nil, true)
+
+	newHabit := &habit.Habit{ID: uuid.New(), UserID: userID, IconID: iconID,
+		Schedule: habit.Schedule{Type: habit.ScheduleDaily}}
+	_, err = store.CreateHabit(newHabit)
+	util.NoError(testing, err)
+	_, err = habit.NewCompletionRepository(database).CreateCompletion(&habit.Completion{ID: uuid.New(),
+		HabitID: newHabit.ID, CompletedAt: time.Now()})
+	util.NoError(testing, err)
+
+	unknownStatus := *newHabit
+	unknownStatus.Status = "deleted"
+	_, err = store.PatchHabit(&unknownStatus, []string{habit.FieldStatus})
+	util.IsEqual(testing, err != nil, true)
+
+	// Deleting the user deletes its habits and their completions.
+	util.NoError(testing, database.Exec("DELETE FROM users WHERE id = ?", userID).Error)
+	var completions int64
+	util.NoError(testing, database.Model(&habit.Completion{}).Count(&completions).Error)
+	util.IsEqual(testing, completions, int64(0))
+	_, err = store.GetHabit(userID, newHabit.ID)
+	util.IsEqual(testing, errors.Is(err, habit.ErrHabitNotFound), true)
+}
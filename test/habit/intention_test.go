@@ -0,0 +1,126 @@
+package habit
+
+import (
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestHabit_Sentence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		habit    habit.Habit
+		expected string
+	}{
+		{
+			name:     "without intention",
+			habit:    habit.Habit{Description: "Read"},
+			expected: "",
+		},
+		{
+			name: "time and location",
+			habit: habit.Habit{Description: "Read a book",
+				Intention: habit.Intention{Time: "07:30", Location: "the kitchen"}},
+			expected: "I will read a book at 07:30 in the kitchen.",
+		},
+		{
+			name: "cue",
+			habit: habit.Habit{Description: "Meditate for one minute",
+				Intention: habit.Intention{Cue: "I pour my morning coffee"}},
+			expected: "When I pour my morning coffee, I will meditate for one minute.",
+		},
+		{
+			name: "avoided habit",
+			habit: habit.Habit{Kind: habit.KindAvoid, Description: "Watch TV after dinner",
+				Intention: habit.Intention{Location: "the living room"}},
+			expected: "I will not watch TV after dinner in the living room.",
+		},
+		{
+			name:     "acronym",
+			habit:    habit.Habit{Description: "HIIT for 20 minutes", Intention: habit.Intention{Time: "18:00"}},
+			expected: "I will HIIT for 20 minutes at 18:00.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			util.IsEqual(t, test.habit.Sentence(), test.expected)
+		})
+	}
+}
+
+func TestHabit_NextReminder(t *testing.T) {
+	t.Parallel()
+
+	created := date(2025, time.April, 1, 8)
+	// Thursday the 10th of April 2025.
+	now := time.Date(2025, time.April, 10, 9, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule habit.Schedule
+		time     string
+		expected time.Time
+	}{
+		{
+			name:     "later today",
+			schedule: daily,
+			time:     "18:00",
+			expected: date(2025, time.April, 10, 18),
+		},
+		{
+			name:     "tomorrow once today's time passed",
+			schedule: habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 3},
+			time:     "07:00",
+			expected: date(2025, time.April, 11, 7),
+		},
+		{
+			name:     "next scheduled weekday",
+			schedule: habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: mondayWednesdayFriday},
+			time:     "06:00",
+			expected: date(2025, time.April, 11, 6),
+		},
+		{
+			name:     "next block of days",
+			schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 4},
+			time:     "20:00",
+			expected: date(2025, time.April, 13, 20),
+		},
+		{
+			name:     "next month",
+			schedule: monthly,
+			time:     "09:00",
+			expected: date(2025, time.May, 1, 9),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			intended := habit.Habit{Schedule: test.schedule, Intention: habit.Intention{Time: test.time},
+				CreatedAt: created}
+
+			reminder, ok, err := intended.NextReminder(now)
+			util.NoError(t, err)
+			util.IsEqual(t, ok, true)
+			if !reminder.Equal(test.expected) {
+				t.Fatalf("Reminder mismatch. Got %v, expected %v", reminder, test.expected)
+			}
+		})
+	}
+
+	// Without a creation time the blocks of every n days are counted from now, like the streak counts them.
+	reminder, ok, err := habit.Habit{Schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 4},
+		Intention: habit.Intention{Time: "20:00"}}.NextReminder(now)
+	util.NoError(t, err)
+	util.IsEqual(t, ok, true)
+	if !reminder.Equal(date(2025, time.April, 10, 20)) {
+		t.Fatalf("Reminder mismatch. Got %v, expected %v", reminder, date(2025, time.April, 10, 20))
+	}
+
+	_, ok, err = habit.Habit{Schedule: daily, Intention: habit.Intention{Cue: "After lunch"}}.NextReminder(now)
+	util.NoError(t, err)
+	util.IsEqual(t, ok, false)
+}
@@ -0,0 +1,66 @@
+package habit
+
+import (
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestCalculateLevelStats(testing *testing.T) {
+	testing.Parallel()
+
+	running := &habit.Habit{Schedule: daily, Levels: habit.Levels{"Run 1 km", "Run 3 km", "Run 5 km"}, Level: 2,
+		CreatedAt: date(2025, time.April, 1, 8)}
+	changes := habit.LevelChanges{{FromLevel: 1, ToLevel: 2, ChangedAt: date(2025, time.April, 5, 12)}}
+
+	// Missed the 3rd at the first level and the 9th at the second, today is still open.
+	completions := completedAt(date(2025, time.April, 1, 9), date(2025, time.April, 2, 9), date(2025, time.April, 4, 9),
+		date(2025, time.April, 5, 9), date(2025, time.April, 6, 9), date(2025, time.April, 7, 9),
+		date(2025, time.April, 8, 9))
+
+	stats, err := habit.CalculateLevelStats(running, changes, completions, nil, date(2025, time.April, 10, 12))
+	util.NoError(testing, err)
+
+	expected := []habit.JsonLevelStats{
+		{Level: 1, Description: "Run 1 km", Days: 4, Completions: 4, Periods: 5, CompletionRate: 0.8},
+		{Level: 2, Description: "Run 3 km", Days: 5, Completions: 3, Periods: 4, CompletionRate: 0.75},
+		{Level: 3, Description: "Run 5 km"},
+	}
+	sinces := []string{"2025-04-01", "2025-04-05", ""}
+	util.IsEqual(testing, len(stats), len(expected))
+	for i, s := range stats {
+		actual := s.ToJson()
+		since := ""
+		if actual.Since != nil {
+			since = *actual.Since
+		}
+		util.IsEqual(testing, since, sinces[i])
+
+		actual.Since = nil
+		util.IsEqual(testing, actual, expected[i])
+	}
+
+	// The missed day does not count while the habit was paused.
+	paused, err := habit.CalculateLevelStats(running, changes, completions,
+		habit.Pauses{{StartedAt: date(2025, time.April, 9, 8), EndedAt: ptr(date(2025, time.April, 9, 20))}},
+		date(2025, time.April, 10, 12))
+	util.NoError(testing, err)
+	util.IsEqual(testing, paused[1].CompletionRate, 1.0)
+}
+
+func TestLevels_Scan(testing *testing.T) {
+	testing.Parallel()
+
+	var levels habit.Levels
+	util.NoError(testing, levels.Scan([]byte(`["Run 1 km","Run 5 km"]`)))
+	util.IsEqual(testing, len(levels), 2)
+	util.IsEqual(testing, levels[1], "Run 5 km")
+
+	util.NoError(testing, levels.Scan("[]"))
+	util.IsEqual(testing, levels == nil, true)
+
+	value, err := levels.Value()
+	util.NoError(testing, err)
+	util.IsEqual(testing, value, any("[]"))
+}
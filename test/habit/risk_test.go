@@ -0,0 +1,148 @@
+package habit
+
+import (
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestCalculateRisk(t *testing.T) {
+	t.Parallel()
+
+	created := date(2025, time.March, 1, 8)
+
+	tests := []struct {
+		name        string
+		habit       habit.Habit
+		completions []time.Time
+		pauses      habit.Pauses
+		now         time.Time
+		expected    habit.Risk
+	}{
+		{
+			name:        "done in the last period",
+			habit:       habit.Habit{Schedule: daily, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 9, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Risk{Status: habit.RiskOnTrack},
+		},
+		{
+			name:        "missed once",
+			habit:       habit.Habit{Schedule: daily, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 8, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected: habit.Risk{Status: habit.RiskAtRisk, Missed: ptr(date(2025, time.April, 9, 0)),
+				Due: ptr(date(2025, time.April, 11, 0))},
+		},
+		{
+			name:        "missed twice",
+			habit:       habit.Habit{Schedule: daily, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 7, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Risk{Status: habit.RiskLapsed, Missed: ptr(date(2025, time.April, 9, 0))},
+		},
+		{
+			name:        "recovered in the current period",
+			habit:       habit.Habit{Schedule: daily, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 7, 9), date(2025, time.April, 10, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Risk{Status: habit.RiskOnTrack},
+		},
+		{
+			name:        "paused periods are skipped",
+			habit:       habit.Habit{Schedule: daily, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 6, 9)},
+			pauses: habit.Pauses{
+				{StartedAt: date(2025, time.April, 8, 20), EndedAt: ptr(date(2025, time.April, 9, 20))},
+			},
+			now: date(2025, time.April, 10, 12),
+			expected: habit.Risk{Status: habit.RiskAtRisk, Missed: ptr(date(2025, time.April, 7, 0)),
+				Due: ptr(date(2025, time.April, 11, 0))},
+		},
+		{
+			name:  "missing the period it was created in does not count",
+			habit: habit.Habit{Schedule: daily, CreatedAt: date(2025, time.April, 8, 20)},
+			now:   date(2025, time.April, 10, 12),
+			expected: habit.Risk{Status: habit.RiskAtRisk, Missed: ptr(date(2025, time.April, 9, 0)),
+				Due: ptr(date(2025, time.April, 11, 0))},
+		},
+		{
+			name:        "weekly habit missed last week",
+			habit:       habit.Habit{Schedule: weekly, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 2, 9)},
+			now:         date(2025, time.April, 16, 12),
+			expected: habit.Risk{Status: habit.RiskAtRisk, Missed: ptr(date(2025, time.April, 7, 0)),
+				Due: ptr(date(2025, time.April, 21, 0))},
+		},
+		{
+			name:        "avoided habit slipped yesterday",
+			habit:       habit.Habit{Kind: habit.KindAvoid, Schedule: weekly, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 9, 22), date(2025, time.April, 10, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected: habit.Risk{Status: habit.RiskAtRisk, Missed: ptr(date(2025, time.April, 9, 0)),
+				Due: ptr(date(2025, time.April, 11, 0))},
+		},
+		{
+			name:        "avoided habit slipped two days in a row",
+			habit:       habit.Habit{Kind: habit.KindAvoid, Schedule: weekly, CreatedAt: created},
+			completions: []time.Time{date(2025, time.April, 8, 22), date(2025, time.April, 9, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Risk{Status: habit.RiskLapsed, Missed: ptr(date(2025, time.April, 9, 0))},
+		},
+		{
+			name:     "archived habit",
+			habit:    habit.Habit{Status: habit.StatusArchived, Schedule: daily, CreatedAt: created},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Risk{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.habit.Status == "" {
+				test.habit.Status = habit.StatusActive
+			}
+			risk, err := habit.CalculateRisk(&test.habit, completedAt(test.completions...), test.pauses, test.now)
+			util.NoError(t, err)
+
+			util.IsEqual(t, risk.Status, test.expected.Status)
+			for _, times := range [][2]*time.Time{{risk.Missed, test.expected.Missed}, {risk.Due, test.expected.Due}} {
+				if (times[0] == nil) != (times[1] == nil) || (times[0] != nil && !times[0].Equal(*times[1])) {
+					t.Fatalf("Risk mismatch. Got %+v, expected %+v", risk.ToJson(), test.expected.ToJson())
+				}
+			}
+		})
+	}
+}
+
+func TestRiskAssessor_AtRisk(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	store := habit.NewRepository(database)
+	completions := habit.NewCompletionRepository(database)
+	userID, iconID := util.NewUser(testing, database), util.NewIcon(testing, database)
+	now := date(2025, time.April, 10, 12)
+
+	// Read was done yesterday, Run the day before and Write three days ago.
+	ids := make([]uuid.UUID, 0, 3)
+	for days, description := range []string{"Read", "Run", "Write"} {
+		newHabit := &habit.Habit{ID: uuid.New(), UserID: userID, Description: description, IconID: iconID,
+			Schedule: daily, CreatedAt: date(2025, time.March, 1, 8)}
+		_, err := store.CreateHabit(newHabit)
+		util.NoError(testing, err)
+		_, err = completions.CreateCompletion(&habit.Completion{ID: uuid.New(), HabitID: newHabit.ID,
+			CompletedAt: now.AddDate(0, 0, -days-1)})
+		util.NoError(testing, err)
+		ids = append(ids, newHabit.ID)
+	}
+
+	atRisk, err := habit.NewRiskAssessor(store).AtRisk(userID, now)
+	util.NoError(testing, err)
+
+	util.IsEqual(testing, len(atRisk), 1)
+	util.IsEqual(testing, atRisk[0].Habit.ID, ids[1])
+	util.IsEqual(testing, atRisk[0].Risk.Due.Equal(date(2025, time.April, 11, 0)), true)
+}
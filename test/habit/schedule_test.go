@@ -0,0 +1,87 @@
+package habit
+
+import (
+	"habitgobackend/cmd/api/config/validation"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func TestSchedule_JsonRoundTrip(testing *testing.T) {
+	testing.Parallel()
+
+	jsonSchedule := habit.JsonSchedule{Type: habit.ScheduleWeekdays, Weekdays: []string{"monday", "Friday"}}
+
+	schedule := jsonSchedule.ToSchedule()
+	util.IsEqual(testing, schedule.Weekdays, 1<<time.Monday|1<<time.Friday)
+
+	weekdays := schedule.ToJson().Weekdays
+	util.IsEqual(testing, len(weekdays), 2)
+	util.IsEqual(testing, weekdays[0], "monday")
+	util.IsEqual(testing, weekdays[1], "friday")
+}
+
+func TestSchedule_LegacyModeTypes(testing *testing.T) {
+	testing.Parallel()
+
+	for _, modeType := range []string{"daily", "weekly", "monthly", "yearly"} {
+		schedule, ok := habit.ScheduleFromModeType(modeType)
+		util.IsEqual(testing, ok, true)
+		util.IsEqual(testing, schedule.ModeType(), modeType)
+	}
+
+	_, ok := habit.ScheduleFromModeType("fortnightly")
+	util.IsEqual(testing, ok, false)
+
+	schedule := habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 3}
+	util.IsEqual(testing, schedule.ModeType(), "")
+}
+
+func TestJsonHabit_ScheduleValidation(testing *testing.T) {
+	testing.Parallel()
+
+	validator := validation.New()
+
+	tests := []struct {
+		name     string
+		modeType string
+		schedule *habit.JsonSchedule
+		valid    bool
+	}{
+		{name: "legacy mode type", modeType: "weekly", valid: true},
+		{name: "capitalised legacy mode type", modeType: "Weekly", valid: true},
+		{name: "upper case legacy mode type", modeType: "DAILY", valid: true},
+		{name: "unknown legacy mode type", modeType: "fortnightly", valid: false},
+		{name: "missing schedule", valid: false},
+		{name: "daily", schedule: &habit.JsonSchedule{Type: "daily"}, valid: true},
+		{name: "unknown type", schedule: &habit.JsonSchedule{Type: "hourly"}, valid: false},
+		{name: "daily with extra fields", schedule: &habit.JsonSchedule{Type: "daily", Times: 2}, valid: false},
+		{name: "times per week", schedule: &habit.JsonSchedule{Type: "times_per_week", Times: 3}, valid: true},
+		{name: "times per week without times", schedule: &habit.JsonSchedule{Type: "times_per_week"}, valid: false},
+		{name: "times per week above seven", schedule: &habit.JsonSchedule{Type: "times_per_week", Times: 8}, valid: false},
+		{name: "weekdays", schedule: &habit.JsonSchedule{Type: "weekdays", Weekdays: []string{"monday"}}, valid: true},
+		{name: "unknown weekday", schedule: &habit.JsonSchedule{Type: "weekdays", Weekdays: []string{"funday"}}, valid: false},
+		{name: "repeated weekday", schedule: &habit.JsonSchedule{Type: "weekdays", Weekdays: []string{"monday", "monday"}}, valid: false},
+		{name: "every n days", schedule: &habit.JsonSchedule{Type: "every_n_days", Interval: 3}, valid: true},
+		{name: "every single day", schedule: &habit.JsonSchedule{Type: "every_n_days", Interval: 1}, valid: false},
+		{name: "monthly", schedule: &habit.JsonSchedule{Type: "monthly", DayOfMonth: 31}, valid: true},
+		{name: "monthly on day zero", schedule: &habit.JsonSchedule{Type: "monthly"}, valid: false},
+		{name: "monthly with weekdays", schedule: &habit.JsonSchedule{Type: "monthly", DayOfMonth: 1, Weekdays: []string{"monday"}}, valid: false},
+	}
+
+	for _, test := range tests {
+		jsonHabit := habit.JsonHabit{
+			Description: "Read",
+			ColourHex:   "#000000",
+			IconID:      "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+			ModeType:    test.modeType,
+			Schedule:    test.schedule,
+		}
+
+		err := validator.Struct(jsonHabit)
+		if (err == nil) != test.valid {
+			testing.Errorf("%s: expected valid %v, got error %v", test.name, test.valid, err)
+		}
+	}
+}
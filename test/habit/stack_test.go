@@ -0,0 +1,117 @@
+package habit
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stackedHabit is a habit created at the given hour of the 1st of April 2025, stacked on the anchor unless
+// it is nil.
+func stackedHabit(description string, hour int, anchor *habit.Habit, timeOfDay string) *habit.Habit {
+	stacked := &habit.Habit{ID: uuid.New(), Description: description, Schedule: daily,
+		Intention: habit.Intention{Time: timeOfDay}, CreatedAt: date(2025, time.April, 1, hour)}
+	if anchor != nil {
+		stacked.AnchorID = &anchor.ID
+	}
+	return stacked
+}
+
+// descriptions lists the descriptions of the habits in their order.
+func descriptions(habits habit.Habits) string {
+	result := make([]string, len(habits))
+	for i, h := range habits {
+		result[i] = h.Description
+	}
+	return strings.Join(result, ", ")
+}
+
+func TestCheckAnchor(t *testing.T) {
+	t.Parallel()
+
+	database := util.NewMemoryDatabase(t)
+	store := habit.NewRepository(database)
+	userID, iconID := util.NewUser(t, database), util.NewIcon(t, database)
+	coffee := stackedHabit("Pour coffee", 1, nil, "07:00")
+	meditate := stackedHabit("Meditate", 2, coffee, "")
+	journal := stackedHabit("Journal", 3, meditate, "")
+	trashed := stackedHabit("Stretch", 4, nil, "")
+	for _, h := range []*habit.Habit{coffee, meditate, journal, trashed} {
+		h.UserID, h.IconID = userID, iconID
+		_, err := store.CreateHabit(h)
+		util.NoError(t, err)
+	}
+	_, err := store.DeleteHabit(userID, trashed.ID, 0)
+	util.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		habit    habit.Habit
+		anchor   uuid.UUID
+		previous *uuid.UUID
+		expected error
+	}{
+		{name: "new stack", habit: habit.Habit{ID: uuid.New()}, anchor: journal.ID},
+		{name: "same anchor", habit: *journal, anchor: meditate.ID, previous: &meditate.ID},
+		{name: "unchanged anchor in the trash", habit: *journal, anchor: trashed.ID, previous: &trashed.ID},
+		{name: "anchor in the trash", habit: *journal, anchor: trashed.ID, previous: &meditate.ID,
+			expected: habit.ErrUnknownAnchor},
+		{name: "missing anchor", habit: habit.Habit{ID: uuid.New()}, anchor: uuid.New(),
+			expected: habit.ErrUnknownAnchor},
+		{name: "itself", habit: *meditate, anchor: meditate.ID, previous: &coffee.ID, expected: habit.ErrStackCycle},
+		{name: "habit stacked on it", habit: *coffee, anchor: journal.ID, expected: habit.ErrStackCycle},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.habit.UserID = userID
+			test.habit.AnchorID = &test.anchor
+
+			err := habit.CheckAnchor(store, &test.habit, test.previous)
+			util.IsEqual(t, errors.Is(err, test.expected), true)
+		})
+	}
+}
+
+func TestStack(testing *testing.T) {
+	testing.Parallel()
+
+	coffee := stackedHabit("Pour coffee", 1, nil, "07:00")
+	meditate := stackedHabit("Meditate", 2, coffee, "")
+	journal := stackedHabit("Journal", 3, meditate, "")
+	vitamins := stackedHabit("Take vitamins", 4, coffee, "06:55")
+	run := stackedHabit("Run", 5, nil, "06:00")
+	habits := habit.Habits{coffee, meditate, journal, vitamins, run}
+
+	util.IsEqual(testing, descriptions(habit.Stack(habits, journal.ID)), "Pour coffee, Meditate, Journal")
+	util.IsEqual(testing, descriptions(habit.Stack(habits, coffee.ID)),
+		"Pour coffee, Take vitamins, Meditate, Journal")
+	util.IsEqual(testing, descriptions(habit.Stack(habits, run.ID)), "Run")
+	util.IsEqual(testing, habit.Stack(habits, uuid.New()) == nil, true)
+}
+
+func TestRoutine(testing *testing.T) {
+	testing.Parallel()
+
+	coffee := stackedHabit("Pour coffee", 1, nil, "07:00")
+	meditate := stackedHabit("Meditate", 2, coffee, "")
+	journal := stackedHabit("Journal", 3, meditate, "")
+	vitamins := stackedHabit("Take vitamins", 4, coffee, "06:55")
+	run := stackedHabit("Run", 5, nil, "06:00")
+	read := stackedHabit("Read", 6, nil, "")
+	// The anchor of floss is not part of the routine, floss starts a stack of its own.
+	floss := stackedHabit("Floss", 7, &habit.Habit{ID: uuid.New()}, "21:00")
+
+	// Habits anchored in a circle are listed last instead of being lost.
+	left := stackedHabit("Left", 8, nil, "")
+	right := stackedHabit("Right", 9, left, "")
+	left.AnchorID = &right.ID
+
+	routine := habit.Routine(habit.Habits{read, journal, floss, left, right, meditate, run, vitamins, coffee})
+	util.IsEqual(testing, descriptions(routine),
+		"Run, Pour coffee, Take vitamins, Meditate, Journal, Floss, Read, Left, Right")
+}
@@ -0,0 +1,530 @@
+package habit
+
+import (
+	"errors"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+var (
+	daily                 = habit.Schedule{Type: habit.ScheduleDaily}
+	weekly                = habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 1}
+	monthly               = habit.Schedule{Type: habit.ScheduleMonthly, DayOfMonth: 1}
+	mondayWednesdayFriday = 1<<time.Monday | 1<<time.Wednesday | 1<<time.Friday
+)
+
+func date(year int, month time.Month, day int, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestCalculateStreak(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		schedule    habit.Schedule
+		completions []time.Time
+		now         time.Time
+		expected    habit.Streak
+	}{
+		{
+			name:     "no completions",
+			schedule: daily,
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{},
+		},
+		{
+			name:     "daily streak including today",
+			schedule: daily,
+			completions: []time.Time{
+				date(2025, time.April, 8, 9), date(2025, time.April, 9, 9), date(2025, time.April, 10, 9),
+			},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:        "daily streak kept while today is still open",
+			schedule:    daily,
+			completions: []time.Time{date(2025, time.April, 8, 9), date(2025, time.April, 9, 23)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Streak{Current: 2, Longest: 2},
+		},
+		{
+			name:     "daily boundary at midnight",
+			schedule: daily,
+			completions: []time.Time{
+				time.Date(2025, time.April, 8, 23, 59, 59, 0, time.UTC), date(2025, time.April, 9, 0),
+			},
+			now:      date(2025, time.April, 9, 1),
+			expected: habit.Streak{Current: 2, Longest: 2},
+		},
+		{
+			name:     "daily streak broken by missed day",
+			schedule: daily,
+			completions: []time.Time{
+				date(2025, time.April, 1, 9), date(2025, time.April, 2, 9), date(2025, time.April, 3, 9),
+				date(2025, time.April, 5, 9),
+			},
+			now:      date(2025, time.April, 5, 12),
+			expected: habit.Streak{Current: 1, Longest: 3, LastBroken: ptr(date(2025, time.April, 4, 0))},
+		},
+		{
+			name:        "daily streak lost after missing yesterday",
+			schedule:    daily,
+			completions: []time.Time{date(2025, time.April, 7, 9), date(2025, time.April, 8, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Streak{Current: 0, Longest: 2, LastBroken: ptr(date(2025, time.April, 9, 0))},
+		},
+		{
+			name:     "multiple completions in one day count once",
+			schedule: daily,
+			completions: []time.Time{
+				date(2025, time.April, 10, 7), date(2025, time.April, 10, 8), date(2025, time.April, 10, 9),
+			},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 1, Longest: 1},
+		},
+		{
+			name:        "future completions are ignored",
+			schedule:    daily,
+			completions: []time.Time{date(2025, time.April, 10, 9), date(2025, time.April, 11, 9)},
+			now:         date(2025, time.April, 10, 12),
+			expected:    habit.Streak{Current: 1, Longest: 1},
+		},
+		{
+			name:     "weekly boundary between sunday and monday",
+			schedule: weekly,
+			completions: []time.Time{
+				date(2025, time.April, 6, 22), date(2025, time.April, 7, 1),
+			},
+			now:      date(2025, time.April, 8, 12),
+			expected: habit.Streak{Current: 2, Longest: 2},
+		},
+		{
+			name:     "weekly streak across the new year",
+			schedule: weekly,
+			completions: []time.Time{
+				date(2024, time.December, 23, 9), date(2024, time.December, 31, 9), date(2025, time.January, 6, 9),
+			},
+			now:      date(2025, time.January, 12, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:     "weekly streak broken by missed week",
+			schedule: weekly,
+			completions: []time.Time{
+				date(2025, time.March, 17, 9), date(2025, time.March, 24, 9), date(2025, time.April, 7, 9),
+			},
+			now:      date(2025, time.April, 8, 12),
+			expected: habit.Streak{Current: 1, Longest: 2, LastBroken: ptr(date(2025, time.March, 31, 0))},
+		},
+		{
+			name:     "monthly boundary at the end of february in a leap year",
+			schedule: monthly,
+			completions: []time.Time{
+				date(2024, time.January, 31, 9), date(2024, time.February, 29, 23), date(2024, time.March, 1, 0),
+			},
+			now:      date(2024, time.March, 15, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:     "monthly streak broken by missed month",
+			schedule: monthly,
+			completions: []time.Time{
+				date(2025, time.January, 31, 9), date(2025, time.March, 1, 9),
+			},
+			now:      date(2025, time.April, 2, 12),
+			expected: habit.Streak{Current: 1, Longest: 1, LastBroken: ptr(date(2025, time.February, 1, 0))},
+		},
+		{
+			name:     "times per week needs all completions within the week",
+			schedule: habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 3},
+			completions: []time.Time{
+				date(2025, time.March, 24, 9), date(2025, time.March, 26, 9), date(2025, time.March, 30, 23),
+				date(2025, time.March, 31, 9), date(2025, time.April, 2, 9),
+				date(2025, time.April, 7, 9), date(2025, time.April, 8, 9), date(2025, time.April, 9, 9),
+			},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 1, Longest: 1, LastBroken: ptr(date(2025, time.March, 31, 0))},
+		},
+		{
+			name:     "weekdays count a late completion until the next scheduled day",
+			schedule: habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: mondayWednesdayFriday},
+			completions: []time.Time{
+				date(2025, time.April, 7, 9), date(2025, time.April, 10, 9), date(2025, time.April, 11, 9),
+			},
+			now:      date(2025, time.April, 13, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:     "weekdays streak broken by missed scheduled day",
+			schedule: habit.Schedule{Type: habit.ScheduleWeekdays, Weekdays: mondayWednesdayFriday},
+			completions: []time.Time{
+				date(2025, time.April, 7, 9), date(2025, time.April, 11, 9),
+			},
+			now:      date(2025, time.April, 12, 12),
+			expected: habit.Streak{Current: 1, Longest: 1, LastBroken: ptr(date(2025, time.April, 9, 0))},
+		},
+		{
+			name:     "every n days counted from the first completion",
+			schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 3},
+			completions: []time.Time{
+				date(2025, time.April, 1, 9), date(2025, time.April, 6, 9), date(2025, time.April, 7, 9),
+			},
+			now:      date(2025, time.April, 9, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:     "every n days boundary at the end of a block",
+			schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 3},
+			completions: []time.Time{
+				date(2025, time.April, 1, 9), time.Date(2025, time.April, 3, 23, 59, 59, 0, time.UTC),
+			},
+			now:      date(2025, time.April, 8, 12),
+			expected: habit.Streak{Current: 0, Longest: 1, LastBroken: ptr(date(2025, time.April, 4, 0))},
+		},
+		{
+			name:     "monthly on a day missing from february",
+			schedule: habit.Schedule{Type: habit.ScheduleMonthly, DayOfMonth: 31},
+			completions: []time.Time{
+				date(2025, time.January, 31, 9), date(2025, time.February, 28, 9), date(2025, time.March, 31, 9),
+			},
+			now:      date(2025, time.April, 2, 12),
+			expected: habit.Streak{Current: 3, Longest: 3},
+		},
+		{
+			name:     "monthly window runs until the day before the next occurrence",
+			schedule: habit.Schedule{Type: habit.ScheduleMonthly, DayOfMonth: 15},
+			completions: []time.Time{
+				date(2025, time.February, 15, 9), date(2025, time.March, 14, 23), date(2025, time.March, 15, 0),
+			},
+			now:      date(2025, time.April, 1, 12),
+			expected: habit.Streak{Current: 2, Longest: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak, err := habit.CalculateStreak(&habit.Habit{Schedule: test.schedule}, completedAt(test.completions...), nil,
+				test.now)
+			util.NoError(t, err)
+
+			util.IsEqual(t, streak.Current, test.expected.Current)
+			util.IsEqual(t, streak.Longest, test.expected.Longest)
+			if (streak.LastBroken == nil) != (test.expected.LastBroken == nil) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", streak.LastBroken, test.expected.LastBroken)
+			}
+			if streak.LastBroken != nil && !streak.LastBroken.Equal(*test.expected.LastBroken) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", *streak.LastBroken, *test.expected.LastBroken)
+			}
+		})
+	}
+}
+
+func TestCalculateStreak_UnknownSchedule(testing *testing.T) {
+	testing.Parallel()
+
+	_, err := habit.CalculateStreak(&habit.Habit{Schedule: habit.Schedule{Type: "fortnightly"}}, nil, nil, time.Now())
+
+	if !errors.Is(err, habit.ErrUnknownSchedule) {
+		testing.Fatalf("Expected ErrUnknownSchedule, got %v", err)
+	}
+}
+
+func TestCalculateStreak_Pauses(t *testing.T) {
+	t.Parallel()
+
+	// Done every day from the 1st to the 10th of April, except from the 4th to the 6th.
+	completions := make([]time.Time, 0, 7)
+	for day := 1; day <= 10; day++ {
+		if day < 4 || day > 6 {
+			completions = append(completions, date(2025, time.April, day, 9))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		schedule habit.Schedule
+		pauses   habit.Pauses
+		now      time.Time
+		expected habit.Streak
+	}{
+		{
+			name:     "without pauses",
+			schedule: daily,
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 4, Longest: 4, CompletionRate: 0.7},
+		},
+		{
+			name:     "paused while missed",
+			schedule: daily,
+			pauses: habit.Pauses{
+				{StartedAt: date(2025, time.April, 3, 20), EndedAt: ptr(date(2025, time.April, 7, 8))},
+			},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 7, Longest: 7, CompletionRate: 1},
+		},
+		{
+			name:     "paused for part of the missed days",
+			schedule: daily,
+			pauses: habit.Pauses{
+				{StartedAt: date(2025, time.April, 4, 12), EndedAt: ptr(date(2025, time.April, 5, 12))},
+			},
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 4, Longest: 4, CompletionRate: 7.0 / 8.0},
+		},
+		{
+			name:     "still paused",
+			schedule: daily,
+			pauses:   habit.Pauses{{StartedAt: date(2025, time.April, 10, 20)}},
+			now:      date(2025, time.April, 14, 12),
+			expected: habit.Streak{Current: 4, Longest: 4, CompletionRate: 0.7},
+		},
+		{
+			name:     "weekly period with a single paused day",
+			schedule: weekly,
+			pauses: habit.Pauses{
+				{StartedAt: date(2025, time.April, 15, 8), EndedAt: ptr(date(2025, time.April, 16, 8))},
+			},
+			now:      date(2025, time.April, 22, 12),
+			expected: habit.Streak{Current: 2, Longest: 2, CompletionRate: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak, err := habit.CalculateStreak(&habit.Habit{Schedule: test.schedule}, completedAt(completions...), test.pauses,
+				test.now)
+			util.NoError(t, err)
+
+			util.IsEqual(t, streak.Current, test.expected.Current)
+			util.IsEqual(t, streak.Longest, test.expected.Longest)
+			util.IsEqual(t, streak.CompletionRate, test.expected.CompletionRate)
+		})
+	}
+}
+
+func TestCalculateStreak_Targets(t *testing.T) {
+	t.Parallel()
+
+	amounts := func(completions ...habit.Completion) habit.Completions {
+		result := make(habit.Completions, 0, len(completions))
+		for _, completion := range completions {
+			result = append(result, &completion)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		schedule    habit.Schedule
+		target      habit.Target
+		completions habit.Completions
+		now         time.Time
+		expected    habit.Streak
+	}{
+		{
+			name:     "sum of a day",
+			schedule: daily,
+			target:   habit.Target{Value: 8, Unit: "glasses", Aggregation: habit.AggregationSum},
+			completions: amounts(
+				habit.Completion{CompletedAt: date(2025, time.April, 8, 9), Amount: 3},
+				habit.Completion{CompletedAt: date(2025, time.April, 8, 18), Amount: 5},
+				habit.Completion{CompletedAt: date(2025, time.April, 9, 9), Amount: 7},
+				habit.Completion{CompletedAt: date(2025, time.April, 10, 9), Amount: 8},
+			),
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 1, Longest: 1, LastBroken: ptr(date(2025, time.April, 9, 0))},
+		},
+		{
+			name:     "max of a week",
+			schedule: weekly,
+			target:   habit.Target{Value: 5, Unit: "km", Aggregation: habit.AggregationMax},
+			completions: amounts(
+				habit.Completion{CompletedAt: date(2025, time.April, 1, 9), Amount: 3},
+				habit.Completion{CompletedAt: date(2025, time.April, 3, 9), Amount: 5.5},
+				habit.Completion{CompletedAt: date(2025, time.April, 8, 9), Amount: 4},
+				habit.Completion{CompletedAt: date(2025, time.April, 10, 9), Amount: 4},
+			),
+			now:      date(2025, time.April, 15, 12),
+			expected: habit.Streak{Current: 0, Longest: 1, LastBroken: ptr(date(2025, time.April, 7, 0))},
+		},
+		{
+			name:     "count ignores amounts",
+			schedule: daily,
+			target:   habit.Target{Value: 2, Aggregation: habit.AggregationCount},
+			completions: amounts(
+				habit.Completion{CompletedAt: date(2025, time.April, 9, 9)},
+				habit.Completion{CompletedAt: date(2025, time.April, 9, 18)},
+				habit.Completion{CompletedAt: date(2025, time.April, 10, 9), Amount: 10},
+			),
+			now:      date(2025, time.April, 10, 12),
+			expected: habit.Streak{Current: 1, Longest: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak, err := habit.CalculateStreak(&habit.Habit{Schedule: test.schedule, Target: test.target},
+				test.completions, nil, test.now)
+			util.NoError(t, err)
+
+			util.IsEqual(t, streak.Current, test.expected.Current)
+			util.IsEqual(t, streak.Longest, test.expected.Longest)
+			if (streak.LastBroken == nil) != (test.expected.LastBroken == nil) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", streak.LastBroken, test.expected.LastBroken)
+			}
+			if streak.LastBroken != nil && !streak.LastBroken.Equal(*test.expected.LastBroken) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", *streak.LastBroken, *test.expected.LastBroken)
+			}
+		})
+	}
+}
+
+func TestCalculateStreak_Avoid(t *testing.T) {
+	t.Parallel()
+
+	avoided := &habit.Habit{Kind: habit.KindAvoid, Schedule: weekly, CreatedAt: date(2025, time.April, 1, 8)}
+
+	tests := []struct {
+		name     string
+		slips    []time.Time
+		now      time.Time
+		expected habit.Streak
+		perWeek  float64
+	}{
+		{
+			name:     "no slips",
+			now:      date(2025, time.April, 7, 12),
+			expected: habit.Streak{Current: 6, Longest: 6, CompletionRate: 1},
+		},
+		{
+			name:  "today is clean once it is over",
+			slips: []time.Time{date(2025, time.April, 6, 22)},
+			now:   date(2025, time.April, 7, 12),
+			expected: habit.Streak{Current: 0, Longest: 5, LastBroken: ptr(date(2025, time.April, 6, 0)),
+				CompletionRate: 5.0 / 6.0},
+			perWeek: 1,
+		},
+		{
+			name: "clean days since the last slip",
+			slips: []time.Time{
+				date(2025, time.April, 3, 9), date(2025, time.April, 3, 21), date(2025, time.April, 5, 9),
+				date(2025, time.April, 20, 9),
+			},
+			now: date(2025, time.April, 14, 12),
+			expected: habit.Streak{Current: 8, Longest: 8, LastBroken: ptr(date(2025, time.April, 5, 0)),
+				CompletionRate: 11.0 / 13.0},
+			perWeek: 1.5,
+		},
+		{
+			name:     "slip before the habit was created",
+			slips:    []time.Time{date(2025, time.March, 25, 9)},
+			now:      date(2025, time.April, 1, 12),
+			expected: habit.Streak{Current: 6, Longest: 6, CompletionRate: 6.0 / 7.0},
+			perWeek:  0.875,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streak, err := habit.CalculateStreak(avoided, completedAt(test.slips...), nil, test.now)
+			util.NoError(t, err)
+
+			util.IsEqual(t, streak.Current, test.expected.Current)
+			util.IsEqual(t, streak.Longest, test.expected.Longest)
+			util.IsEqual(t, streak.CompletionRate, test.expected.CompletionRate)
+			util.IsEqual(t, streak.Slips.PerWeek, test.perWeek)
+			if (streak.LastBroken == nil) != (test.expected.LastBroken == nil) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", streak.LastBroken, test.expected.LastBroken)
+			}
+			if streak.LastBroken != nil && !streak.LastBroken.Equal(*test.expected.LastBroken) {
+				t.Fatalf("LastBroken mismatch. Got %v, expected %v", *streak.LastBroken, *test.expected.LastBroken)
+			}
+		})
+	}
+}
+
+func TestCalculateProgress(t *testing.T) {
+	t.Parallel()
+
+	now := date(2025, time.April, 10, 12)
+	completions := habit.Completions{
+		{CompletedAt: date(2025, time.April, 7, 9), Amount: 4},
+		{CompletedAt: date(2025, time.April, 10, 8), Amount: 2},
+		{CompletedAt: date(2025, time.April, 10, 11), Amount: 3.5},
+		{CompletedAt: date(2025, time.April, 10, 20), Amount: 3},
+	}
+
+	tests := []struct {
+		name     string
+		habit    habit.Habit
+		expected habit.JsonProgress
+	}{
+		{
+			name:  "sum of today",
+			habit: habit.Habit{Schedule: daily, Target: habit.Target{Value: 8, Unit: "glasses", Aggregation: habit.AggregationSum}},
+			expected: habit.JsonProgress{Value: 5.5, Goal: 8, Unit: "glasses", PeriodStart: "2025-04-10",
+				PeriodEnd: "2025-04-10"},
+		},
+		{
+			name:  "max of the week",
+			habit: habit.Habit{Schedule: weekly, Target: habit.Target{Value: 4, Unit: "km", Aggregation: habit.AggregationMax}},
+			expected: habit.JsonProgress{Value: 4, Goal: 4, Unit: "km", Done: true, PeriodStart: "2025-04-07",
+				PeriodEnd: "2025-04-13"},
+		},
+		{
+			name:  "completions of a yes/no habit",
+			habit: habit.Habit{Schedule: habit.Schedule{Type: habit.ScheduleTimesPerWeek, Times: 4}},
+			expected: habit.JsonProgress{Value: 3, Goal: 4, PeriodStart: "2025-04-07",
+				PeriodEnd: "2025-04-13"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			progress, err := habit.CalculateProgress(&test.habit, completions, now)
+			util.NoError(t, err)
+			util.IsEqual(t, progress.ToJson(), test.expected)
+		})
+	}
+}
+
+func TestPeriodAnchor(testing *testing.T) {
+	testing.Parallel()
+
+	// Blocks of three days count from the 2nd, when the habit was created, not from its first completion on
+	// the 4th: the 2nd to the 4th and the 5th to the 7th were both done.
+	created := &habit.Habit{Schedule: habit.Schedule{Type: habit.ScheduleEveryNDays, Interval: 3},
+		CreatedAt: date(2025, time.April, 2, 8)}
+	completions := completedAt(date(2025, time.April, 4, 9), date(2025, time.April, 5, 9))
+	now := date(2025, time.April, 8, 12)
+
+	streak, err := habit.CalculateStreak(created, completions, nil, now)
+	util.NoError(testing, err)
+	util.IsEqual(testing, streak.Current, 2)
+
+	progress, err := habit.CalculateProgress(created, completions, now)
+	util.NoError(testing, err)
+	util.IsEqual(testing, progress.ToJson().PeriodStart, "2025-04-08")
+
+	// Without a creation time the blocks count from the first completion, both fall in the 4th to the 6th.
+	streak, err = habit.CalculateStreak(&habit.Habit{Schedule: created.Schedule}, completions, nil, now)
+	util.NoError(testing, err)
+	util.IsEqual(testing, streak.Current, 1)
+}
+
+// completedAt returns completions of a yes/no habit done at the given times.
+func completedAt(times ...time.Time) habit.Completions {
+	completions := make(habit.Completions, 0, len(times))
+	for _, completedAt := range times {
+		completions = append(completions, &habit.Completion{CompletedAt: completedAt})
+	}
+	return completions
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"habitgobackend/cmd/api/config/storage"
+	"habitgobackend/cmd/api/resource/health"
+	"habitgobackend/test/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const versionQuery = "SELECT COALESCE\\(\\(\\s+SELECT version_id FROM goose_db_version (.+) WHERE is_applied"
+
+func ready(testing *testing.T, healthAPI *health.Api) (int, health.JsonHealth) {
+	recorder := httptest.NewRecorder()
+	healthAPI.Ready(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	result := health.JsonHealth{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+	return recorder.Code, result
+}
+
+func TestApi_HealthCheck(testing *testing.T) {
+	testing.Parallel()
+
+	database, _, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	healthAPI := health.New(database, 0)
+
+	recorder := httptest.NewRecorder()
+	healthAPI.HealthCheck(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	healthAPI.Drain()
+
+	recorder = httptest.NewRecorder()
+	healthAPI.HealthCheck(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	util.IsEqual(testing, recorder.Code, http.StatusServiceUnavailable)
+
+	recorder = httptest.NewRecorder()
+	healthAPI.Live(recorder, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+}
+
+func TestApi_Ready(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	healthAPI := health.New(database, 6)
+
+	mock.ExpectQuery(versionQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(6))
+
+	code, result := ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusOK)
+	util.IsEqual(testing, result.Status, health.StatusOK)
+	util.IsEqual(testing, result.Checks["database"].Status, health.StatusOK)
+	util.IsEqual(testing, result.Checks["migrations"].Status, health.StatusOK)
+	util.IsEqual(testing, *result.Checks["migrations"].Version, int64(6))
+
+	healthAPI.Drain()
+	mock.ExpectQuery(versionQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(6))
+
+	code, result = ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusServiceUnavailable)
+	util.IsEqual(testing, result.Status, health.StatusFail)
+}
+
+func TestApi_ReadyOutdatedMigrations(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	healthAPI := health.New(database, 6)
+
+	mock.ExpectQuery(versionQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(5))
+
+	code, result := ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusServiceUnavailable)
+	util.IsEqual(testing, result.Status, health.StatusFail)
+	util.IsEqual(testing, result.Checks["database"].Status, health.StatusOK)
+	util.IsEqual(testing, result.Checks["migrations"].Status, health.StatusFail)
+	util.IsEqual(testing, *result.Checks["migrations"].Version, int64(5))
+	util.IsEqual(testing, *result.Checks["migrations"].ExpectedVersion, int64(6))
+}
+
+func TestApi_ReadyRolledBackMigration(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	version, err := storage.MigrationVersion(storage.DriverMemory)
+	util.NoError(testing, err)
+
+	healthAPI := health.New(database, version)
+
+	code, result := ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusOK)
+	util.IsEqual(testing, *result.Checks["migrations"].Version, version)
+
+	// A rolled back migration stays in the version table, marked as not applied.
+	util.NoError(testing, database.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, ?)",
+		version, false).Error)
+
+	code, result = ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusServiceUnavailable)
+	util.IsEqual(testing, result.Checks["migrations"].Status, health.StatusFail)
+	util.IsEqual(testing, *result.Checks["migrations"].Version, int64(0))
+	util.IsEqual(testing, *result.Checks["migrations"].ExpectedVersion, version)
+}
+
+func TestApi_ReadyDatabaseDown(testing *testing.T) {
+	testing.Parallel()
+
+	sqlDatabase, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	util.NoError(testing, err)
+
+	mock.ExpectPing()
+	database, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDatabase}), &gorm.Config{})
+	util.NoError(testing, err)
+
+	healthAPI := health.New(database, 0)
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	code, result := ready(testing, healthAPI)
+	util.IsEqual(testing, code, http.StatusServiceUnavailable)
+	util.IsEqual(testing, result.Checks["database"].Status, health.StatusFail)
+	util.IsEqual(testing, result.Checks["database"].Error, "connection refused")
+	_, checked := result.Checks["migrations"]
+	util.IsEqual(testing, checked, false)
+}
@@ -0,0 +1,201 @@
+package icon
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/test/util"
+	"testing"
+)
+
+const (
+	pngBase64 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNk+A8AAQUBAScY42YAAAAASUVORK5CYII="
+	pngID     = "431ced6916a2a21a156e38701afe55bbd7f88969fbbfc56d7fe099d47f265460"
+	svg       = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`
+	svgID     = "962f0f96dab1c473067b0656a652156516ac633bc56ff564f3ebba2c412c6652"
+)
+
+var iconColumns = []string{"id", "content_type", "size", "data", "created_at"}
+
+func TestIcon_FromBytes(t *testing.T) {
+	png, err := base64.StdEncoding.DecodeString(pngBase64)
+	util.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		id          string
+		contentType string
+		err         error
+	}{
+		{name: "png", data: png, id: pngID, contentType: icon.ContentTypePNG},
+		{name: "svg", data: []byte(svg), id: svgID, contentType: icon.ContentTypeSVG},
+		{name: "gif", data: []byte("GIF89a"), err: icon.ErrUnsupportedContent},
+		{name: "jpeg", data: []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), err: icon.ErrUnsupportedContent},
+		{name: "text", data: []byte("not an icon"), err: icon.ErrUnsupportedContent},
+		{name: "html", data: []byte("<html><svg/></html>"), err: icon.ErrUnsupportedContent},
+		{name: "unclosed svg", data: []byte("<svg><g></svg>"), err: icon.ErrUnsupportedContent},
+		{name: "empty", data: []byte{}, err: icon.ErrUnsupportedContent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(testing *testing.T) {
+			result, err := icon.FromBytes(test.data)
+			if test.err != nil {
+				util.IsEqual(testing, errors.Is(err, test.err), true)
+				return
+			}
+			util.NoError(testing, err)
+			util.IsEqual(testing, result.ID, test.id)
+			util.IsEqual(testing, result.ContentType, test.contentType)
+			util.IsEqual(testing, result.Size, int64(len(test.data)))
+		})
+	}
+}
+
+func TestIcon_FromDataURL(testing *testing.T) {
+	testing.Parallel()
+
+	result, err := icon.FromDataURL("data:image/png;base64," + pngBase64)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, pngID)
+
+	result, err = icon.FromDataURL(pngBase64)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, pngID)
+
+	_, err = icon.FromDataURL("data:image/png;base64,FISSHH")
+	util.IsEqual(testing, errors.Is(err, icon.ErrUnsupportedContent), true)
+
+	// Legacy icons were not checked, a GIF labelled as an SVG is still a GIF.
+	_, err = icon.FromDataURL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte("GIF89a")))
+	util.IsEqual(testing, errors.Is(err, icon.ErrUnsupportedContent), true)
+}
+
+func TestLocalStore_SaveAndLoad(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	store := icon.NewLocalStore(testing.TempDir(), database)
+	ctx := context.Background()
+
+	newIcon, err := icon.FromBytes([]byte(svg))
+	util.NoError(testing, err)
+
+	created, err := store.Save(ctx, newIcon)
+	util.NoError(testing, err)
+	util.IsEqual(testing, created, true)
+
+	created, err = store.Save(ctx, newIcon)
+	util.NoError(testing, err)
+	util.IsEqual(testing, created, false)
+
+	exists, err := store.Exists(ctx, svgID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, exists, true)
+
+	loaded, err := store.Load(ctx, svgID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, loaded.ID, svgID)
+	util.IsEqual(testing, loaded.ContentType, icon.ContentTypeSVG)
+	util.IsEqual(testing, string(loaded.Data), svg)
+
+	// Habits reference icons by a foreign key, so the icon has a row without its data.
+	row := icon.Icon{}
+	util.NoError(testing, database.Where("id = ?", svgID).First(&row).Error)
+	util.IsEqual(testing, row.Size, int64(len(svg)))
+	util.IsEqual(testing, len(row.Data), 0)
+}
+
+func TestLocalStore_FileWithoutRow(testing *testing.T) {
+	testing.Parallel()
+
+	directory := testing.TempDir()
+	ctx := context.Background()
+
+	newIcon, err := icon.FromBytes([]byte(svg))
+	util.NoError(testing, err)
+	_, err = icon.NewLocalStore(directory, util.NewMemoryDatabase(testing)).Save(ctx, newIcon)
+	util.NoError(testing, err)
+
+	// The same directory with a database which has no row for the icon, as before icons had rows.
+	store := icon.NewLocalStore(directory, util.NewMemoryDatabase(testing))
+	exists, err := store.Exists(ctx, svgID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, exists, false)
+
+	created, err := store.Save(ctx, newIcon)
+	util.NoError(testing, err)
+	util.IsEqual(testing, created, true)
+
+	exists, err = store.Exists(ctx, svgID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, exists, true)
+}
+
+func TestLocalStore_LoadMissing(testing *testing.T) {
+	testing.Parallel()
+
+	store := icon.NewLocalStore(testing.TempDir(), util.NewMemoryDatabase(testing))
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, pngID)
+	util.IsEqual(testing, errors.Is(err, icon.ErrIconNotFound), true)
+
+	_, err = store.Load(ctx, "../../etc/passwd")
+	util.IsEqual(testing, errors.Is(err, icon.ErrIconNotFound), true)
+
+	exists, err := store.Exists(ctx, pngID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, exists, false)
+}
+
+func TestDatabaseStore_Save(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	store := icon.NewDatabaseStore(database)
+
+	newIcon, err := icon.FromBytes([]byte(svg))
+	util.NoError(testing, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO \"icons\" (.+) ON CONFLICT DO NOTHING").
+		WithArgs(svgID, icon.ContentTypeSVG, int64(len(svg)), []byte(svg), util.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	created, err := store.Save(context.Background(), newIcon)
+	util.NoError(testing, err)
+	util.IsEqual(testing, created, false)
+}
+
+func TestDatabaseStore_Load(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	store := icon.NewDatabaseStore(database)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"icons\" WHERE id = (.+)").
+		WithArgs(svgID, 1).
+		WillReturnRows(sqlmock.NewRows(iconColumns).
+			AddRow(svgID, icon.ContentTypeSVG, len(svg), []byte(svg), nil))
+
+	loaded, err := store.Load(context.Background(), svgID)
+	util.NoError(testing, err)
+	util.IsEqual(testing, loaded.ID, svgID)
+	util.IsEqual(testing, string(loaded.Data), svg)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"icons\" WHERE id = (.+)").
+		WithArgs(pngID, 1).
+		WillReturnRows(sqlmock.NewRows(iconColumns))
+
+	_, err = store.Load(context.Background(), pngID)
+	util.IsEqual(testing, errors.Is(err, icon.ErrIconNotFound), true)
+}
@@ -0,0 +1,198 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/idempotency"
+	"habitgobackend/test/util"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// creator answers every request like a create endpoint, with a new ID each time, and counts the calls.
+type creator struct {
+	calls  atomic.Int32
+	status int
+}
+
+func (c *creator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls.Add(1)
+	io.Copy(io.Discard, r.Body)
+
+	id := uuid.NewString()
+	w.Header().Set("Location", "/habits/"+id)
+	w.Header().Set(headers.CREATED_ID, id)
+	w.WriteHeader(c.status)
+}
+
+// newHandler returns the middleware in front of a creator answering with the status, and the database the
+// users of the test have to be stored in.
+func newHandler(testing *testing.T, window time.Duration, status int) (http.Handler, *creator, *gorm.DB) {
+	handler := &creator{status: status}
+	database := util.NewMemoryDatabase(testing)
+	return idempotency.New(database, window).Middleware(handler), handler, database
+}
+
+func post(handler http.Handler, userID uuid.UUID, key string, body string) *httptest.ResponseRecorder {
+	request := util.NewRequest(http.MethodPost, "/habits", body, nil, userID)
+	if key != "" {
+		request.Header.Set(idempotency.Header, key)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func problemType(testing *testing.T, recorder *httptest.ResponseRecorder) string {
+	problem := e.Problem{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&problem))
+	return problem.Type
+}
+
+func TestKeys_Replay(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, database := newHandler(testing, time.Hour, http.StatusCreated)
+	userID := util.NewUser(testing, database)
+
+	first := post(handler, userID, "retry-1", `{"description":"Read"}`)
+	util.IsEqual(testing, first.Code, http.StatusCreated)
+
+	replayed := post(handler, userID, "retry-1", `{"description":"Read"}`)
+	util.IsEqual(testing, replayed.Code, http.StatusCreated)
+	util.IsEqual(testing, replayed.Header().Get("Location"), first.Header().Get("Location"))
+	util.IsEqual(testing, replayed.Header().Get(headers.CREATED_ID), first.Header().Get(headers.CREATED_ID))
+	util.IsEqual(testing, replayed.Header().Get(idempotency.ReplayedHeader), "true")
+	util.IsEqual(testing, creator.calls.Load(), int32(1))
+
+	// Keys belong to a user, another user's request with the same key is handled.
+	other := post(handler, util.NewUser(testing, database), "retry-1", `{"description":"Read"}`)
+	util.IsEqual(testing, other.Code, http.StatusCreated)
+	util.IsEqual(testing, other.Header().Get(idempotency.ReplayedHeader), "")
+	util.IsEqual(testing, creator.calls.Load(), int32(2))
+}
+
+func TestKeys_WithoutKey(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, database := newHandler(testing, time.Hour, http.StatusCreated)
+	userID := util.NewUser(testing, database)
+
+	post(handler, userID, "", `{}`)
+	post(handler, userID, "", `{}`)
+	util.IsEqual(testing, creator.calls.Load(), int32(2))
+
+	request := util.NewRequest(http.MethodPut, "/habits/id", `{}`, nil, userID)
+	request.Header.Set(idempotency.Header, "put")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	util.IsEqual(testing, creator.calls.Load(), int32(4))
+}
+
+func TestKeys_ReusedWithDifferentRequest(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, database := newHandler(testing, time.Hour, http.StatusCreated)
+	userID := util.NewUser(testing, database)
+
+	post(handler, userID, "reused", `{"description":"Read"}`)
+
+	recorder := post(handler, userID, "reused", `{"description":"Write"}`)
+	util.IsEqual(testing, recorder.Code, http.StatusUnprocessableEntity)
+	util.IsEqual(testing, problemType(testing, recorder), e.IdempotencyKeyReused.Type)
+	util.IsEqual(testing, creator.calls.Load(), int32(1))
+}
+
+func TestKeys_InvalidKey(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, _ := newHandler(testing, time.Hour, http.StatusCreated)
+
+	recorder := post(handler, uuid.New(), strings.Repeat("k", 256), `{}`)
+	util.IsEqual(testing, recorder.Code, http.StatusBadRequest)
+	util.IsEqual(testing, problemType(testing, recorder), e.InvalidIdempotencyKey.Type)
+	util.IsEqual(testing, creator.calls.Load(), int32(0))
+}
+
+func TestKeys_ServerErrorIsNotStored(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, database := newHandler(testing, time.Hour, http.StatusInternalServerError)
+	userID := util.NewUser(testing, database)
+
+	post(handler, userID, "failing", `{}`)
+	recorder := post(handler, userID, "failing", `{}`)
+	util.IsEqual(testing, recorder.Code, http.StatusInternalServerError)
+	util.IsEqual(testing, recorder.Header().Get(idempotency.ReplayedHeader), "")
+	util.IsEqual(testing, creator.calls.Load(), int32(2))
+}
+
+func TestKeys_ExpiredKey(testing *testing.T) {
+	testing.Parallel()
+
+	handler, creator, database := newHandler(testing, time.Nanosecond, http.StatusCreated)
+	userID := util.NewUser(testing, database)
+
+	post(handler, userID, "expiring", `{}`)
+	time.Sleep(time.Millisecond)
+
+	recorder := post(handler, userID, "expiring", `{"description":"Different"}`)
+	util.IsEqual(testing, recorder.Code, http.StatusCreated)
+	util.IsEqual(testing, creator.calls.Load(), int32(2))
+}
+
+func TestKeys_InProgress(testing *testing.T) {
+	testing.Parallel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	database := util.NewMemoryDatabase(testing)
+	handler := idempotency.New(database, time.Hour).Middleware(slow)
+	userID := util.NewUser(testing, database)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(handler, userID, "slow", `{}`) }()
+	<-started
+
+	recorder := post(handler, userID, "slow", `{}`)
+	util.IsEqual(testing, recorder.Code, http.StatusConflict)
+	util.IsEqual(testing, problemType(testing, recorder), e.IdempotencyKeyInProgress.Type)
+
+	close(release)
+	util.IsEqual(testing, (<-done).Code, http.StatusCreated)
+	util.IsEqual(testing, post(handler, userID, "slow", `{}`).Code, http.StatusCreated)
+}
+
+func TestRepository_DeleteExpired(testing *testing.T) {
+	testing.Parallel()
+
+	database := util.NewMemoryDatabase(testing)
+	repository := idempotency.NewRepository(database)
+	ctx := context.Background()
+	now := time.Now()
+
+	for key, expiresAt := range map[string]time.Time{"old": now.Add(-time.Minute), "new": now.Add(time.Minute)} {
+		record := &idempotency.Record{UserID: util.NewUser(testing, database), Key: key, ExpiresAt: expiresAt}
+		reserved, err := repository.Reserve(ctx, record)
+		util.NoError(testing, err)
+		util.IsEqual(testing, reserved, true)
+	}
+
+	deleted, err := repository.DeleteExpired(ctx, now)
+	util.NoError(testing, err)
+	util.IsEqual(testing, deleted, int64(1))
+}
@@ -0,0 +1,79 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"habitgobackend/cmd/api/resource/auth"
+	"habitgobackend/cmd/api/resource/user"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	client = &http.Client{Transport: userTransport{}}
+
+	loginOnce   sync.Once
+	accessToken string
+	loginErr    error
+)
+
+// userTransport sends every request as a user registered and logged in once for the whole test run.
+type userTransport struct{}
+
+func (userTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	loginOnce.Do(func() {
+		accessToken, loginErr = registerAndLogin()
+	})
+	if loginErr != nil {
+		return nil, loginErr
+	}
+
+	request = request.Clone(request.Context())
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func registerAndLogin() (string, error) {
+	registration := user.JsonRegistration{
+		Email:    fmt.Sprintf("smoke-%s@habits.test", uuid.New()),
+		Name:     "Smoke test",
+		Password: "smoke-test-password",
+	}
+
+	resp, err := postJSON("/users", registration)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to register smoke test user, status: %v", resp.StatusCode)
+	}
+
+	loginResp, err := postJSON("/auth/login", auth.JsonLogin{Email: registration.Email, Password: registration.Password})
+	if err != nil {
+		return "", err
+	}
+	defer loginResp.Body.Close()
+
+	if loginResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to log in smoke test user, status: %v", loginResp.StatusCode)
+	}
+
+	tokens := auth.JsonTokens{}
+	if err := json.NewDecoder(loginResp.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+func postJSON(path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return http.Post(baseURL+path, "application/json", bytes.NewBuffer(payload))
+}
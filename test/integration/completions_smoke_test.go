@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/test/util"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSmoke_Completions(testing *testing.T) {
+	ClearDb(testing)
+	newHabit := habit.JsonHabit{
+		Description: "Test habit with completions",
+		ColourHex:   "#FF5733",
+		IconBase64:  "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNk+A8AAQUBAScY42YAAAAASUVORK5CYII=",
+		ModeType:    "daily",
+	}
+
+	habitJSON, err := json.Marshal(newHabit)
+	if err != nil {
+		testing.Fatalf("Failed to marshal habit: %s", err)
+	}
+
+	resp, err := client.Post(fmt.Sprintf("%s/habits", baseURL), "application/json", bytes.NewBuffer(habitJSON))
+	if err != nil {
+		testing.Fatalf("Failed to create habit: %s", err)
+	}
+	defer resp.Body.Close()
+
+	habitId := resp.Header.Get(headers.CREATED_ID)
+	completedAt := time.Date(2025, 4, 10, 7, 30, 0, 0, time.UTC)
+
+	completionJSON, err := json.Marshal(habit.JsonCompletion{CompletedAt: completedAt, Note: "Before work"})
+	if err != nil {
+		testing.Fatalf("Failed to marshal completion: %s", err)
+	}
+
+	completionResp, err := client.Post(
+		fmt.Sprintf("%s/habits/%s/completions", baseURL, habitId),
+		"application/json",
+		bytes.NewBuffer(completionJSON),
+	)
+	if err != nil {
+		testing.Fatalf("Failed to create completion: %s", err)
+	}
+	defer completionResp.Body.Close()
+
+	util.IsEqual(testing, completionResp.StatusCode, http.StatusCreated)
+	completionId := completionResp.Header.Get(headers.CREATED_ID)
+
+	completions := getCompletions(testing, habitId, "from=2025-04-10&to=2025-04-10")
+	util.IsEqual(testing, len(completions), 1)
+	util.IsEqual(testing, completions[0].ID, completionId)
+	util.IsEqual(testing, completions[0].Note, "Before work")
+	util.IsEqual(testing, completions[0].CompletedAt.Equal(completedAt), true)
+
+	util.IsEqual(testing, len(getCompletions(testing, habitId, "from=2025-04-11")), 0)
+
+	deleteReq, err := http.NewRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/habits/%s/completions/%s", baseURL, habitId, completionId),
+		nil,
+	)
+	if err != nil {
+		testing.Fatalf("Failed to create DELETE request: %s", err)
+	}
+
+	deleteResp, err := client.Do(deleteReq)
+	if err != nil {
+		testing.Fatalf("Failed to delete completion: %s", err)
+	}
+	defer deleteResp.Body.Close()
+
+	util.IsEqual(testing, deleteResp.StatusCode, http.StatusOK)
+	util.IsEqual(testing, len(getCompletions(testing, habitId, "")), 0)
+}
+
+func getCompletions(testing *testing.T, habitId string, query string) []habit.JsonCompletion {
+	resp, err := client.Get(fmt.Sprintf("%s/habits/%s/completions?%s", baseURL, habitId, query))
+	if err != nil {
+		testing.Fatalf("Failed to get completions: %s", err)
+	}
+	defer resp.Body.Close()
+
+	util.IsEqual(testing, resp.StatusCode, http.StatusOK)
+
+	var completions []habit.JsonCompletion
+	if err = json.NewDecoder(resp.Body).Decode(&completions); err != nil {
+		testing.Fatalf("Failed to decode completions: %s", err)
+	}
+	return completions
+}
@@ -4,12 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	e "habitgobackend/cmd/api/resource/common/error"
 	headers "habitgobackend/cmd/api/resource/common/helpers"
 	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/cmd/api/resource/idempotency"
 	"habitgobackend/test/util"
 	"io"
 	"net/http"
-	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -17,24 +18,28 @@ import (
 
 const (
 	baseURL = "http://localhost:8080/v1"
+
+	// SHA-256 hashes of the PNG and SVG icons sent inline by the tests.
+	pngIconID = "431ced6916a2a21a156e38701afe55bbd7f88969fbbfc56d7fe099d47f265460"
+	svgIconID = "962f0f96dab1c473067b0656a652156516ac633bc56ff564f3ebba2c412c6652"
 )
 
 func ClearDb(testing *testing.T) {
-	resp, err := http.Get(fmt.Sprintf("%s/habits", baseURL))
+	resp, err := client.Get(fmt.Sprintf("%s/habits?limit=100", baseURL))
 	if err != nil {
 		panic(err)
 	}
 	defer resp.Body.Close()
 
-	habits := &habit.Habits{}
+	habits := &habit.JsonHabits{}
 
 	if err = json.NewDecoder(resp.Body).Decode(habits); err != nil {
 		testing.Fatalf("Failed to decode habits: %s", err)
 	}
 
-	for item := range *habits {
+	for item := range habits.Habits {
 		request, err := http.NewRequest("DELETE",
-			fmt.Sprintf("%s/habits/%v", baseURL, (*habits)[item].ID.String()),
+			fmt.Sprintf("%s/habits/%v", baseURL, habits.Habits[item].ID),
 			bytes.NewBuffer([]byte{}),
 		)
 		if err != nil {
@@ -42,7 +47,6 @@ func ClearDb(testing *testing.T) {
 			return
 		}
 
-		client := &http.Client{}
 		delRequest, err := client.Do(request)
 		if err != nil || (delRequest.StatusCode != http.StatusOK && delRequest.StatusCode != http.StatusNotFound) {
 			testing.Fatalf("Failed to create DELETE request: %v status: %v", err, delRequest.StatusCode)
@@ -54,7 +58,7 @@ func ClearDb(testing *testing.T) {
 
 func TestSmoke_GetHabits(testing *testing.T) {
 	ClearDb(testing)
-	resp, err := http.Get(fmt.Sprintf("%s/habits", baseURL))
+	resp, err := client.Get(fmt.Sprintf("%s/habits", baseURL))
 
 	if err != nil {
 		testing.Fatalf("Failed to get habits: %s", err)
@@ -62,15 +66,14 @@ func TestSmoke_GetHabits(testing *testing.T) {
 
 	util.IsEqual(testing, resp.StatusCode, http.StatusOK)
 
-	habits := &habit.Habits{}
+	habits := &habit.JsonHabits{}
 
 	if err = json.NewDecoder(resp.Body).Decode(habits); err != nil {
 		testing.Fatalf("Failed to decode habits: %s", err)
 	}
 
-	expected := habit.Habits{}
-
-	util.HabitsEqual(testing, *habits, expected)
+	util.IsEqual(testing, len(habits.Habits), 0)
+	util.IsEqual(testing, habits.NextCursor == nil, true)
 }
 
 func TestSmoke_CreateHabit(testing *testing.T) {
@@ -87,7 +90,7 @@ func TestSmoke_CreateHabit(testing *testing.T) {
 		testing.Fatalf("Failed to marshal habit: %s", err)
 	}
 
-	resp, err := http.Post(
+	resp, err := client.Post(
 		fmt.Sprintf("%s/habits", baseURL),
 		"application/json",
 		bytes.NewBuffer(habitJSON),
@@ -105,7 +108,8 @@ func TestSmoke_CreateHabit(testing *testing.T) {
 
 	util.IsEqual(testing, createdHabit.Description, newHabit.Description)
 	util.IsEqual(testing, createdHabit.ColourHex, newHabit.ColourHex)
-	util.IsEqual(testing, createdHabit.IconBase64, newHabit.IconBase64)
+	util.IsEqual(testing, createdHabit.IconID, pngIconID)
+	util.IsEqual(testing, createdHabit.IconURL, "/v1/icons/"+pngIconID)
 	util.IsEqual(testing, createdHabit.ModeType, newHabit.ModeType)
 
 	_, err = uuid.Parse(createdHabit.ID)
@@ -114,6 +118,62 @@ func TestSmoke_CreateHabit(testing *testing.T) {
 	}
 }
 
+func TestSmoke_CreateHabitWithIdempotencyKey(testing *testing.T) {
+	ClearDb(testing)
+	key := uuid.NewString()
+
+	create := func() *http.Response {
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/habits", baseURL), bytes.NewBufferString(
+			`{"description":"Retried habit","colourHex":"#FF5733","iconId":"`+pngIconID+`","modeType":"daily"}`))
+		if err != nil {
+			testing.Fatalf("Failed to create POST request: %s", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(idempotency.Header, key)
+
+		resp, err := client.Do(request)
+		if err != nil {
+			testing.Fatalf("Failed to create habit: %s", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	first := create()
+	util.IsEqual(testing, first.StatusCode, http.StatusCreated)
+
+	retry := create()
+	util.IsEqual(testing, retry.StatusCode, http.StatusCreated)
+	util.IsEqual(testing, retry.Header.Get(headers.CREATED_ID), first.Header.Get(headers.CREATED_ID))
+	util.IsEqual(testing, retry.Header.Get("Location"), first.Header.Get("Location"))
+
+	habits := getHabitsPage(testing, "")
+	util.IsEqual(testing, len(habits.Habits), 1)
+}
+
+func TestSmoke_BatchHabits(testing *testing.T) {
+	ClearDb(testing)
+	habitBody := `{"description":"Batched habit","colourHex":"#FF5733","iconId":"` + pngIconID + `","modeType":"daily"}`
+
+	resp, err := client.Post(fmt.Sprintf("%s/habits:batch", baseURL), "application/json", bytes.NewBufferString(
+		`{"atomic":true,"operations":[{"op":"create","habit":`+habitBody+`},{"op":"create","habit":`+habitBody+`}]}`))
+	if err != nil {
+		testing.Fatalf("Failed to send batch: %s", err)
+	}
+	defer resp.Body.Close()
+	util.IsEqual(testing, resp.StatusCode, http.StatusOK)
+
+	batch := habit.JsonBatchResults{}
+	if err = json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		testing.Fatalf("Failed to decode batch results: %s", err)
+	}
+	util.IsEqual(testing, batch.Committed, true)
+	util.IsEqual(testing, batch.Results[1].Status, http.StatusCreated)
+
+	habits := getHabitsPage(testing, "")
+	util.IsEqual(testing, len(habits.Habits), 2)
+}
+
 func TestSmoke_GetHabitById(testing *testing.T) {
 	ClearDb(testing)
 	newHabit := habit.JsonHabit{
@@ -128,7 +188,7 @@ func TestSmoke_GetHabitById(testing *testing.T) {
 		testing.Fatalf("Failed to marshal habit: %s", err)
 	}
 
-	resp, err := http.Post(
+	resp, err := client.Post(
 		fmt.Sprintf("%s/habits", baseURL),
 		"application/json",
 		bytes.NewBuffer(habitJSON),
@@ -144,7 +204,7 @@ func TestSmoke_GetHabitById(testing *testing.T) {
 	util.IsEqual(testing, retrievedHabit.ID, createdHabitId)
 	util.IsEqual(testing, retrievedHabit.Description, newHabit.Description)
 	util.IsEqual(testing, retrievedHabit.ColourHex, newHabit.ColourHex)
-	util.IsEqual(testing, retrievedHabit.IconBase64, newHabit.IconBase64)
+	util.IsEqual(testing, retrievedHabit.IconID, pngIconID)
 	util.IsEqual(testing, retrievedHabit.ModeType, newHabit.ModeType)
 }
 
@@ -162,7 +222,7 @@ func TestSmoke_UpdateHabit(testing *testing.T) {
 		testing.Fatalf("Failed to marshal habit: %s", err)
 	}
 
-	resp, err := http.Post(
+	resp, err := client.Post(
 		fmt.Sprintf("%s/habits", baseURL),
 		"application/json",
 		bytes.NewBuffer(habitJSON),
@@ -177,7 +237,7 @@ func TestSmoke_UpdateHabit(testing *testing.T) {
 		ID:          createdHabitId,
 		Description: "Updated test habit",
 		ColourHex:   "#9933FF",
-		IconBase64:  "data:image/png;base64,FISSHH",
+		IconBase64:  "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHZpZXdCb3g9IjAgMCAxIDEiLz4=",
 		ModeType:    "yearly",
 	}
 
@@ -196,7 +256,6 @@ func TestSmoke_UpdateHabit(testing *testing.T) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	putResp, err := client.Do(req)
 	if err != nil {
 		testing.Fatalf("Failed to update habit: %s", err)
@@ -210,7 +269,7 @@ func TestSmoke_UpdateHabit(testing *testing.T) {
 	util.IsEqual(testing, returnedHabit.ID, updatedHabit.ID)
 	util.IsEqual(testing, returnedHabit.Description, updatedHabit.Description)
 	util.IsEqual(testing, returnedHabit.ColourHex, updatedHabit.ColourHex)
-	util.IsEqual(testing, returnedHabit.IconBase64, updatedHabit.IconBase64)
+	util.IsEqual(testing, returnedHabit.IconID, svgIconID)
 	util.IsEqual(testing, returnedHabit.ModeType, updatedHabit.ModeType)
 }
 
@@ -228,7 +287,7 @@ func TestSmoke_InvalidScenarios(testing *testing.T) {
 		testing.Fatalf("Failed to marshal habit: %s", err)
 	}
 
-	resp, err := http.Post(
+	resp, err := client.Post(
 		fmt.Sprintf("%s/habits", baseURL),
 		"application/json",
 		bytes.NewBuffer(habitJSON),
@@ -240,21 +299,20 @@ func TestSmoke_InvalidScenarios(testing *testing.T) {
 
 	util.IsEqual(testing, resp.StatusCode, http.StatusUnprocessableEntity)
 
-	var errorResp map[string]interface{}
-	if err = json.NewDecoder(resp.Body).Decode(&errorResp); err != nil {
-		testing.Fatalf("Failed to decode error response: %s", err)
-	}
+	util.IsEqual(testing, resp.Header.Get("Content-Type"), e.ContentType)
 
-	errorMsg, ok := errorResp["error"].(string)
-	if !ok {
-		testing.Fatalf("Error response does not contain 'error' field")
+	problem := e.Problem{}
+	if err = json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		testing.Fatalf("Failed to decode error response: %s", err)
 	}
 
-	if !strings.Contains(errorMsg, "Could not create entity") {
-		testing.Errorf("Error message expected: Could not create entity, actual: %s", errorMsg)
+	util.IsEqual(testing, problem.Status, http.StatusUnprocessableEntity)
+	util.IsEqual(testing, problem.Type, e.ValidationFailure.Type)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "description" {
+		testing.Errorf("Expected a single error for the description, actual: %+v", problem.Errors)
 	}
 
-	getResp, err := http.Get(fmt.Sprintf("%s/habits/not-a-valid-uuid", baseURL))
+	getResp, err := client.Get(fmt.Sprintf("%s/habits/not-a-valid-uuid", baseURL))
 	if err != nil {
 		testing.Fatalf("Failed to get habit with invalid ID: %s", err)
 	}
@@ -269,7 +327,7 @@ func TestSmoke_NotFoundErrors(testing *testing.T) {
 	ClearDb(testing)
 	randomUUID := uuid.New().String()
 
-	resp, err := http.Get(fmt.Sprintf("%s/habits/%s", baseURL, randomUUID))
+	resp, err := client.Get(fmt.Sprintf("%s/habits/%s", baseURL, randomUUID))
 	if err != nil {
 		testing.Fatalf("Failed to get non-existent habit: %s", err)
 	}
@@ -300,7 +358,6 @@ func TestSmoke_NotFoundErrors(testing *testing.T) {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	putResp, err := client.Do(req)
 	if err != nil {
 		testing.Fatalf("Failed to update non-existent habit: %s", err)
@@ -328,7 +385,7 @@ func TestSmoke_NotFoundErrors(testing *testing.T) {
 }
 
 func getHabit(testing *testing.T, habitId string) habit.JsonHabit {
-	getResp, err := http.Get(fmt.Sprintf("%s/habits/%s", baseURL, habitId))
+	getResp, err := client.Get(fmt.Sprintf("%s/habits/%s", baseURL, habitId))
 	if err != nil {
 		testing.Fatalf("Failed to get habit by ID: %s", err)
 	}
@@ -347,3 +404,57 @@ func getHabit(testing *testing.T, habitId string) habit.JsonHabit {
 	}
 	return retrievedHabit
 }
+
+func TestSmoke_PaginateHabits(testing *testing.T) {
+	ClearDb(testing)
+	for _, description := range []string{"Walk", "Read", "Meditate"} {
+		habitJSON, err := json.Marshal(habit.JsonHabit{
+			Description: description,
+			ColourHex:   "#FF5733",
+			IconBase64:  "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNk+A8AAQUBAScY42YAAAAASUVORK5CYII=",
+			ModeType:    "daily",
+		})
+		if err != nil {
+			testing.Fatalf("Failed to marshal habit: %s", err)
+		}
+
+		resp, err := client.Post(fmt.Sprintf("%s/habits", baseURL), "application/json", bytes.NewBuffer(habitJSON))
+		if err != nil {
+			testing.Fatalf("Failed to create habit: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	firstPage := getHabitsPage(testing, "limit=2&sort=description")
+	util.IsEqual(testing, len(firstPage.Habits), 2)
+	util.IsEqual(testing, firstPage.Habits[0].Description, "Meditate")
+	util.IsEqual(testing, firstPage.Habits[1].Description, "Read")
+	if firstPage.NextCursor == nil {
+		testing.Fatalf("Expected a next cursor on the first page")
+	}
+
+	secondPage := getHabitsPage(testing, "limit=2&sort=description&cursor="+*firstPage.NextCursor)
+	util.IsEqual(testing, len(secondPage.Habits), 1)
+	util.IsEqual(testing, secondPage.Habits[0].Description, "Walk")
+	util.IsEqual(testing, secondPage.NextCursor == nil, true)
+
+	searched := getHabitsPage(testing, "search=rea")
+	util.IsEqual(testing, len(searched.Habits), 1)
+	util.IsEqual(testing, searched.Habits[0].Description, "Read")
+}
+
+func getHabitsPage(testing *testing.T, query string) habit.JsonHabits {
+	resp, err := client.Get(fmt.Sprintf("%s/habits?%s", baseURL, query))
+	if err != nil {
+		testing.Fatalf("Failed to get habits: %s", err)
+	}
+	defer resp.Body.Close()
+
+	util.IsEqual(testing, resp.StatusCode, http.StatusOK)
+
+	var page habit.JsonHabits
+	if err = json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		testing.Fatalf("Failed to decode habits: %s", err)
+	}
+	return page
+}
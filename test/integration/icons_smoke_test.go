@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/test/util"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSmoke_UploadIcon(testing *testing.T) {
+	png, err := base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNk+A8AAQUBAScY42YAAAAASUVORK5CYII=")
+	util.NoError(testing, err)
+
+	resp, err := client.Post(fmt.Sprintf("%s/icons", baseURL), icon.ContentTypePNG, bytes.NewBuffer(png))
+	if err != nil {
+		testing.Fatalf("Failed to upload icon: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		testing.Fatalf("Unexpected upload status: %d", resp.StatusCode)
+	}
+
+	uploaded := icon.JsonIcon{}
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		testing.Fatalf("Failed to decode icon: %s", err)
+	}
+	util.IsEqual(testing, uploaded.ID, pngIconID)
+	util.IsEqual(testing, uploaded.ContentType, icon.ContentTypePNG)
+
+	duplicateResp, err := client.Post(fmt.Sprintf("%s/icons", baseURL), icon.ContentTypePNG, bytes.NewBuffer(png))
+	if err != nil {
+		testing.Fatalf("Failed to upload icon: %s", err)
+	}
+	defer duplicateResp.Body.Close()
+	util.IsEqual(testing, duplicateResp.StatusCode, http.StatusOK)
+
+	getResp, err := http.Get(fmt.Sprintf("%s/icons/%s", baseURL, uploaded.ID))
+	if err != nil {
+		testing.Fatalf("Failed to get icon: %s", err)
+	}
+	defer getResp.Body.Close()
+
+	util.IsEqual(testing, getResp.StatusCode, http.StatusOK)
+	util.IsEqual(testing, getResp.Header.Get("Content-Type"), icon.ContentTypePNG)
+
+	content, err := io.ReadAll(getResp.Body)
+	util.NoError(testing, err)
+	util.IsEqual(testing, bytes.Equal(content, png), true)
+
+	invalidResp, err := client.Post(fmt.Sprintf("%s/icons", baseURL), "image/gif", bytes.NewBufferString("GIF89a"))
+	if err != nil {
+		testing.Fatalf("Failed to upload icon: %s", err)
+	}
+	defer invalidResp.Body.Close()
+	util.IsEqual(testing, invalidResp.StatusCode, http.StatusUnsupportedMediaType)
+}
@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/scorecard"
+	"habitgobackend/test/util"
+	"net/http"
+	"testing"
+)
+
+func TestSmoke_Scorecard(testing *testing.T) {
+	resp, err := client.Post(fmt.Sprintf("%s/scorecard/entries", baseURL), "application/json",
+		bytes.NewBufferString(`{"behaviour":"Check my phone","rating":"negative"}`))
+	if err != nil {
+		testing.Fatalf("Failed to create scorecard entry: %s", err)
+	}
+	defer resp.Body.Close()
+	util.IsEqual(testing, resp.StatusCode, http.StatusCreated)
+	entryId := resp.Header.Get(headers.CREATED_ID)
+
+	scorecardResp, err := client.Get(fmt.Sprintf("%s/scorecard", baseURL))
+	if err != nil {
+		testing.Fatalf("Failed to get scorecard: %s", err)
+	}
+	defer scorecardResp.Body.Close()
+	util.IsEqual(testing, scorecardResp.StatusCode, http.StatusOK)
+
+	card := scorecard.JsonScorecard{}
+	if err = json.NewDecoder(scorecardResp.Body).Decode(&card); err != nil {
+		testing.Fatalf("Failed to decode scorecard: %s", err)
+	}
+	last := card.Entries[len(card.Entries)-1]
+	util.IsEqual(testing, last.ID, entryId)
+	util.IsEqual(testing, last.Position, len(card.Entries))
+
+	summaryResp, err := client.Get(fmt.Sprintf("%s/scorecard/summary?interval=day", baseURL))
+	if err != nil {
+		testing.Fatalf("Failed to get scorecard summary: %s", err)
+	}
+	defer summaryResp.Body.Close()
+	util.IsEqual(testing, summaryResp.StatusCode, http.StatusOK)
+
+	summary := scorecard.JsonSummary{}
+	if err = json.NewDecoder(summaryResp.Body).Decode(&summary); err != nil {
+		testing.Fatalf("Failed to decode scorecard summary: %s", err)
+	}
+	util.IsEqual(testing, summary.Current, card.Tally)
+}
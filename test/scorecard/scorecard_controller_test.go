@@ -0,0 +1,249 @@
+package scorecard
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/config/validation"
+	e "habitgobackend/cmd/api/resource/common/error"
+	headers "habitgobackend/cmd/api/resource/common/helpers"
+	"habitgobackend/cmd/api/resource/habit"
+	"habitgobackend/cmd/api/resource/scorecard"
+	"habitgobackend/test/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fixture is a scorecard API with a single user owning a habit and a scorecard with one entry.
+type fixture struct {
+	api     *scorecard.Api
+	userID  uuid.UUID
+	habitID uuid.UUID
+	entryID uuid.UUID
+}
+
+func newFixture(testing *testing.T, databaseDown bool) fixture {
+	database := util.NewMemoryDatabase(testing)
+	f := fixture{userID: util.NewUser(testing, database), habitID: uuid.New(), entryID: uuid.New()}
+
+	habits := habit.NewRepository(database)
+	_, err := habits.CreateHabit(&habit.Habit{ID: f.habitID, UserID: f.userID, Description: "Read",
+		IconID: util.NewIcon(testing, database), Schedule: habit.Schedule{Type: habit.ScheduleDaily}})
+	util.NoError(testing, err)
+
+	_, err = scorecard.NewRepository(database).CreateEntry(&scorecard.Entry{ID: f.entryID, UserID: f.userID,
+		Behaviour: "Wake up", Rating: scorecard.RatingNeutral})
+	util.NoError(testing, err)
+
+	if databaseDown {
+		sqlDatabase, err := database.DB()
+		util.NoError(testing, err)
+		util.NoError(testing, sqlDatabase.Close())
+	}
+
+	f.api = scorecard.New(database, habits, validation.New())
+	return f
+}
+
+func TestApi_Handlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		databaseDown bool
+		handler      func(*scorecard.Api, http.ResponseWriter, *http.Request)
+		method       string
+		target       string
+		body         func(fixture) string
+		params       func(fixture) map[string]string
+		status       int
+		problem      e.Problem
+	}{
+		{name: "get scorecard", handler: (*scorecard.Api).GetScorecard, method: http.MethodGet, target: "/scorecard",
+			status: http.StatusOK},
+		{name: "get scorecard while database is down", databaseDown: true, handler: (*scorecard.Api).GetScorecard,
+			method: http.MethodGet, target: "/scorecard", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+
+		{name: "create entry", handler: (*scorecard.Api).CreateEntry, method: http.MethodPost,
+			target: "/scorecard/entries", body: text(`{"behaviour":"Check my phone","rating":"negative"}`),
+			status: http.StatusCreated},
+		{name: "create entry linked to habit", handler: (*scorecard.Api).CreateEntry, method: http.MethodPost,
+			target: "/scorecard/entries", body: func(f fixture) string {
+				return `{"behaviour":"Read","rating":"positive","habitId":"` + f.habitID.String() + `"}`
+			}, status: http.StatusCreated},
+		{name: "create entry linked to unknown habit", handler: (*scorecard.Api).CreateEntry, method: http.MethodPost,
+			target: "/scorecard/entries",
+			body:   text(`{"behaviour":"Read","rating":"positive","habitId":"` + uuid.NewString() + `"}`),
+			status: http.StatusUnprocessableEntity, problem: e.UnknownHabit},
+		{name: "create entry with unknown rating", handler: (*scorecard.Api).CreateEntry, method: http.MethodPost,
+			target: "/scorecard/entries", body: text(`{"behaviour":"Read","rating":"great"}`),
+			status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+		{name: "create entry with malformed body", handler: (*scorecard.Api).CreateEntry, method: http.MethodPost,
+			target: "/scorecard/entries", body: text(`{"behaviour":`), status: http.StatusBadRequest,
+			problem: e.JsonDecodeFailure},
+		{name: "create entry while database is down", databaseDown: true, handler: (*scorecard.Api).CreateEntry,
+			method: http.MethodPost, target: "/scorecard/entries", body: text(`{"behaviour":"Read","rating":"positive"}`),
+			status: http.StatusInternalServerError, problem: e.CreateFailure},
+
+		{name: "update entry", handler: (*scorecard.Api).UpdateEntry, method: http.MethodPut,
+			target: "/scorecard/entries/id", params: entryParam,
+			body: text(`{"behaviour":"Wake up at 6","rating":"positive"}`), status: http.StatusOK},
+		{name: "update entry with invalid id", handler: (*scorecard.Api).UpdateEntry, method: http.MethodPut,
+			target: "/scorecard/entries/id", params: param("id", "not-a-uuid"), status: http.StatusBadRequest,
+			problem: e.InvalidUrlRequest},
+		{name: "update missing entry", handler: (*scorecard.Api).UpdateEntry, method: http.MethodPut,
+			target: "/scorecard/entries/id", params: param("id", uuid.NewString()),
+			body: text(`{"behaviour":"Wake up","rating":"positive"}`), status: http.StatusNotFound,
+			problem: e.EntryNotFound},
+		{name: "update entry without behaviour", handler: (*scorecard.Api).UpdateEntry, method: http.MethodPut,
+			target: "/scorecard/entries/id", params: entryParam, body: text(`{"rating":"positive"}`),
+			status: http.StatusUnprocessableEntity, problem: e.ValidationFailure},
+
+		{name: "delete entry", handler: (*scorecard.Api).DeleteEntry, method: http.MethodDelete,
+			target: "/scorecard/entries/id", params: entryParam, status: http.StatusOK},
+		{name: "delete missing entry", handler: (*scorecard.Api).DeleteEntry, method: http.MethodDelete,
+			target: "/scorecard/entries/id", params: param("id", uuid.NewString()), status: http.StatusNotFound,
+			problem: e.EntryNotFound},
+		{name: "delete entry while database is down", databaseDown: true, handler: (*scorecard.Api).DeleteEntry,
+			method: http.MethodDelete, target: "/scorecard/entries/id", params: entryParam,
+			status: http.StatusInternalServerError, problem: e.DeleteFailure},
+
+		{name: "order scorecard", handler: (*scorecard.Api).OrderScorecard, method: http.MethodPut,
+			target: "/scorecard/order", body: func(f fixture) string {
+				return `{"entryIds":["` + f.entryID.String() + `"]}`
+			}, status: http.StatusOK},
+		{name: "order scorecard with unknown entry", handler: (*scorecard.Api).OrderScorecard, method: http.MethodPut,
+			target: "/scorecard/order", body: text(`{"entryIds":["` + uuid.NewString() + `"]}`),
+			status: http.StatusUnprocessableEntity, problem: e.InvalidOrder},
+		{name: "order scorecard twice listing an entry", handler: (*scorecard.Api).OrderScorecard,
+			method: http.MethodPut, target: "/scorecard/order", body: func(f fixture) string {
+				return `{"entryIds":["` + f.entryID.String() + `","` + f.entryID.String() + `"]}`
+			}, status: http.StatusUnprocessableEntity, problem: e.InvalidOrder},
+		{name: "order scorecard with invalid id", handler: (*scorecard.Api).OrderScorecard, method: http.MethodPut,
+			target: "/scorecard/order", body: text(`{"entryIds":["first"]}`), status: http.StatusUnprocessableEntity,
+			problem: e.ValidationFailure},
+
+		{name: "get summary", handler: (*scorecard.Api).GetSummary, method: http.MethodGet,
+			target: "/scorecard/summary?interval=day", status: http.StatusOK},
+		{name: "get summary with unknown interval", handler: (*scorecard.Api).GetSummary, method: http.MethodGet,
+			target: "/scorecard/summary?interval=year", status: http.StatusBadRequest, problem: e.InvalidQueryParams},
+		{name: "get summary with from after to", handler: (*scorecard.Api).GetSummary, method: http.MethodGet,
+			target: "/scorecard/summary?from=2025-05-01&to=2025-04-01", status: http.StatusBadRequest,
+			problem: e.InvalidQueryParams},
+		{name: "get summary with too many periods", handler: (*scorecard.Api).GetSummary, method: http.MethodGet,
+			target: "/scorecard/summary?interval=day&from=2020-01-01&to=2025-01-01", status: http.StatusBadRequest,
+			problem: e.InvalidQueryParams},
+		{name: "get summary while database is down", databaseDown: true, handler: (*scorecard.Api).GetSummary,
+			method: http.MethodGet, target: "/scorecard/summary", status: http.StatusInternalServerError,
+			problem: e.DatabaseConnectionFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(testing *testing.T) {
+			f := newFixture(testing, test.databaseDown)
+
+			var body string
+			if test.body != nil {
+				body = test.body(f)
+			}
+			var params map[string]string
+			if test.params != nil {
+				params = test.params(f)
+			}
+
+			recorder := httptest.NewRecorder()
+			test.handler(f.api, recorder, util.NewRequest(test.method, test.target, body, params, f.userID))
+
+			util.IsEqual(testing, recorder.Code, test.status)
+			if test.problem.Type == "" {
+				return
+			}
+
+			util.IsEqual(testing, recorder.Header().Get("Content-Type"), e.ContentType)
+			problem := e.Problem{}
+			util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&problem))
+			util.IsEqual(testing, problem.Type, test.problem.Type)
+			util.IsEqual(testing, problem.Status, test.status)
+		})
+	}
+}
+
+func TestApi_Scorecard(testing *testing.T) {
+	testing.Parallel()
+
+	f := newFixture(testing, false)
+
+	create := func(body string) string {
+		recorder := httptest.NewRecorder()
+		f.api.CreateEntry(recorder, util.NewRequest(http.MethodPost, "/scorecard/entries", body, nil, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusCreated)
+		return recorder.Header().Get(headers.CREATED_ID)
+	}
+	get := func() scorecard.JsonScorecard {
+		recorder := httptest.NewRecorder()
+		f.api.GetScorecard(recorder, util.NewRequest(http.MethodGet, "/scorecard", "", nil, f.userID))
+		util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+		result := scorecard.JsonScorecard{}
+		util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&result))
+		return result
+	}
+
+	phoneID := create(`{"behaviour":"Check my phone","rating":"negative"}`)
+	readID := create(`{"behaviour":"Read","rating":"positive","habitId":"` + f.habitID.String() + `"}`)
+
+	result := get()
+	util.IsEqual(testing, len(result.Entries), 3)
+	util.IsEqual(testing, result.Entries[1].ID, phoneID)
+	util.IsEqual(testing, result.Entries[2].Position, 3)
+	util.IsEqual(testing, result.Entries[2].HabitID, f.habitID.String())
+	util.IsEqual(testing, result.Tally, scorecard.JsonTally{Positive: 1, Negative: 1, Neutral: 1, Balance: 0})
+
+	recorder := httptest.NewRecorder()
+	order := `{"entryIds":["` + readID + `","` + f.entryID.String() + `","` + phoneID + `"]}`
+	f.api.OrderScorecard(recorder, util.NewRequest(http.MethodPut, "/scorecard/order", order, nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	recorder = httptest.NewRecorder()
+	f.api.DeleteEntry(recorder, util.NewRequest(http.MethodDelete, "/scorecard/entries/id", "",
+		map[string]string{"id": f.entryID.String()}, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+
+	// The entries after the removed one moved up.
+	result = get()
+	util.IsEqual(testing, len(result.Entries), 2)
+	util.IsEqual(testing, result.Entries[0].ID, readID)
+	util.IsEqual(testing, result.Entries[1].ID, phoneID)
+	util.IsEqual(testing, result.Entries[1].Position, 2)
+
+	recorder = httptest.NewRecorder()
+	f.api.UpdateEntry(recorder, util.NewRequest(http.MethodPut, "/scorecard/entries/id",
+		`{"behaviour":"Check my phone after breakfast","rating":"neutral"}`, map[string]string{"id": phoneID},
+		f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+	updated := scorecard.JsonEntry{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&updated))
+	util.IsEqual(testing, updated.Position, 2)
+	util.IsEqual(testing, updated.Rating, scorecard.RatingNeutral)
+
+	recorder = httptest.NewRecorder()
+	f.api.GetSummary(recorder, util.NewRequest(http.MethodGet, "/scorecard/summary?interval=month", "", nil, f.userID))
+	util.IsEqual(testing, recorder.Code, http.StatusOK)
+	summary := scorecard.JsonSummary{}
+	util.NoError(testing, json.NewDecoder(recorder.Body).Decode(&summary))
+	util.IsEqual(testing, len(summary.Periods), 12)
+	util.IsEqual(testing, summary.Current, scorecard.JsonTally{Positive: 1, Neutral: 1, Balance: 1})
+	util.IsEqual(testing, summary.Periods[11].JsonTally, summary.Current)
+	util.IsEqual(testing, summary.Periods[10].JsonTally, scorecard.JsonTally{})
+}
+
+func param(key string, value string) func(fixture) map[string]string {
+	return func(fixture) map[string]string { return map[string]string{key: value} }
+}
+
+func entryParam(f fixture) map[string]string {
+	return map[string]string{"id": f.entryID.String()}
+}
+
+func text(body string) func(fixture) string {
+	return func(fixture) string { return body }
+}
@@ -0,0 +1,66 @@
+package scorecard
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"habitgobackend/cmd/api/resource/scorecard"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSummarise(testing *testing.T) {
+	testing.Parallel()
+
+	// The phone was checked first thing in the morning until it was rated neutral once it waited until
+	// after breakfast, smoking was removed from the scorecard after quitting.
+	phone := &scorecard.Entry{ID: uuid.New(), Rating: scorecard.RatingNeutral, CreatedAt: date(2025, time.March, 3)}
+	read := &scorecard.Entry{ID: uuid.New(), Rating: scorecard.RatingPositive, CreatedAt: date(2025, time.March, 4)}
+	smoke := &scorecard.Entry{ID: uuid.New(), Rating: scorecard.RatingNegative, CreatedAt: date(2025, time.March, 3),
+		DeletedAt: gorm.DeletedAt{Time: date(2025, time.March, 12), Valid: true}}
+	changes := scorecard.RatingChanges{
+		{EntryID: phone.ID, Rating: scorecard.RatingNegative, RatedAt: phone.CreatedAt},
+		{EntryID: smoke.ID, Rating: scorecard.RatingNegative, RatedAt: smoke.CreatedAt},
+		{EntryID: read.ID, Rating: scorecard.RatingPositive, RatedAt: read.CreatedAt},
+		{EntryID: phone.ID, Rating: scorecard.RatingNeutral, RatedAt: date(2025, time.March, 18)},
+	}
+	entries := scorecard.Entries{phone, read, smoke}
+
+	// Wednesday the 19th of March 2025.
+	now := date(2025, time.March, 19)
+	periods, err := scorecard.Summarise(entries, changes, date(2025, time.February, 26), date(2025, time.April, 1),
+		scorecard.IntervalWeek, now)
+	util.NoError(testing, err)
+
+	expected := []scorecard.JsonPeriod{
+		{Start: "2025-02-24", End: "2025-03-02", JsonTally: scorecard.JsonTally{}},
+		{Start: "2025-03-03", End: "2025-03-09", JsonTally: scorecard.JsonTally{Positive: 1, Negative: 2, Balance: -1}},
+		{Start: "2025-03-10", End: "2025-03-16", JsonTally: scorecard.JsonTally{Positive: 1, Negative: 1}},
+		{Start: "2025-03-17", End: "2025-03-23", JsonTally: scorecard.JsonTally{Positive: 1, Neutral: 1, Balance: 1}},
+	}
+	util.IsEqual(testing, len(periods), len(expected))
+	for i, period := range periods {
+		util.IsEqual(testing, period.ToJson(), expected[i])
+	}
+
+	util.IsEqual(testing, scorecard.CountRatings(entries).ToJson(),
+		scorecard.JsonTally{Positive: 1, Neutral: 1, Balance: 1})
+}
+
+func TestSummarise_Months(testing *testing.T) {
+	testing.Parallel()
+
+	// The range ends right before March.
+	periods, err := scorecard.Summarise(nil, nil, date(2025, time.January, 31),
+		time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), scorecard.IntervalMonth, date(2025, time.June, 1))
+	util.NoError(testing, err)
+
+	util.IsEqual(testing, len(periods), 2)
+	util.IsEqual(testing, periods[0].ToJson().End, "2025-01-31")
+	util.IsEqual(testing, periods[1].ToJson().Start, "2025-02-01")
+	util.IsEqual(testing, periods[1].ToJson().End, "2025-02-28")
+}
@@ -0,0 +1,163 @@
+package user
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"habitgobackend/cmd/api/resource/user"
+	"habitgobackend/test/util"
+	"testing"
+	"time"
+)
+
+var userColumns = []string{"id", "email", "name", "password_hash", "created_at", "updated_at"}
+
+func TestRepository_CreateUser(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	newUser := &user.User{ID: uuid.New(), Email: "james@clear.com", Name: "James", PasswordHash: "hash"}
+
+	// The first user is given the habits created before there were users.
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO \"users\" ").
+		WithArgs(newUser.ID, "james@clear.com", "James", "hash", util.AnyTime{}, util.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"users\"").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("^UPDATE habits SET user_id = \\$1 WHERE user_id IS NULL").
+		WithArgs(newUser.ID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	result, err := repository.CreateUser(newUser)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, newUser.ID)
+	util.IsEqual(testing, result.CreatedAt.IsZero(), false)
+
+	// Later users are not.
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO \"users\" ").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("^SELECT count\\(\\*\\) FROM \"users\"").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectCommit()
+
+	_, err = repository.CreateUser(&user.User{ID: uuid.New(), Email: "jane@clear.com", Name: "Jane", PasswordHash: "hash"})
+	util.NoError(testing, err)
+	util.NoError(testing, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CreateUserEmailTaken(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	// The insert of a request which raced another one for the email violates the unique constraint.
+	mock.ExpectBegin()
+	mock.ExpectExec("^INSERT INTO \"users\" ").WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	_, err = repository.CreateUser(&user.User{ID: uuid.New(), Email: "james@clear.com", Name: "James", PasswordHash: "hash"})
+	util.IsEqual(testing, errors.Is(err, user.ErrEmailTaken), true)
+
+	memory := user.NewRepository(util.NewMemoryDatabase(testing))
+	_, err = memory.CreateUser(&user.User{ID: uuid.New(), Email: "james@clear.com", Name: "James", PasswordHash: "hash"})
+	util.NoError(testing, err)
+	_, err = memory.CreateUser(&user.User{ID: uuid.New(), Email: "james@clear.com", Name: "Jim", PasswordHash: "hash"})
+	util.IsEqual(testing, errors.Is(err, user.ErrEmailTaken), true)
+}
+
+func TestRepository_GetUser(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	id := uuid.New()
+	createdAt := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM \"users\" WHERE id = (.+)").
+		WithArgs(id, 1).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(id, "james@clear.com", "James", "hash", createdAt, createdAt))
+
+	result, err := repository.GetUser(id)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, id)
+	util.IsEqual(testing, result.Email, "james@clear.com")
+	util.IsEqual(testing, result.Name, "James")
+	util.IsEqual(testing, result.PasswordHash, "hash")
+}
+
+func TestRepository_GetUserByEmail(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	id := uuid.New()
+	createdAt := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM \"users\" WHERE email = (.+)").
+		WithArgs("james@clear.com", 1).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(id, "james@clear.com", "James", "hash", createdAt, createdAt))
+
+	result, err := repository.GetUserByEmail("james@clear.com")
+	util.NoError(testing, err)
+	util.IsEqual(testing, result.ID, id)
+}
+
+func TestRepository_UpdateUser(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE \"users\" SET").
+		WithArgs("new@clear.com", "Jim", "new hash", util.AnyTime{}, id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := repository.UpdateUser(&user.User{ID: id, Email: "new@clear.com", Name: "Jim", PasswordHash: "new hash"})
+	util.NoError(testing, err)
+	util.IsEqual(testing, result, 1)
+}
+
+func TestRepository_DeleteUser(testing *testing.T) {
+	testing.Parallel()
+
+	database, mock, err := util.NewMockDatabase()
+	util.NoError(testing, err)
+
+	repository := user.NewRepository(database)
+
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^DELETE FROM \"users\" WHERE (.+)").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	result, err := repository.DeleteUser(id)
+	util.NoError(testing, err)
+	util.IsEqual(testing, result, 1)
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"habitgobackend/cmd/api/config/storage"
+	"habitgobackend/cmd/api/resource/icon"
+	"habitgobackend/cmd/api/resource/user"
+	"habitgobackend/cmd/config"
+	"testing"
+	"time"
+)
+
+// NewMemoryDatabase opens a migrated in-memory SQLite database, the database of the memory driver.
+func NewMemoryDatabase(testing *testing.T) *gorm.DB {
+	database, err := storage.Open(config.DatabaseConfig{Driver: storage.DriverMemory}, &gorm.Config{Logger: logger.Discard})
+	NoError(testing, err)
+	return database
+}
+
+// NewUser stores a user for the rows which have to belong to one, and returns its ID.
+func NewUser(testing *testing.T, database *gorm.DB) uuid.UUID {
+	id := uuid.New()
+	NoError(testing, database.Create(&user.User{ID: id, Email: id.String() + "@example.com", Name: "Test",
+		PasswordHash: "hash", CreatedAt: time.Now(), UpdatedAt: time.Now()}).Error)
+	return id
+}
+
+// NewIcon stores an icon for the habits of a test, which must have one, and returns its ID.
+func NewIcon(testing *testing.T, database *gorm.DB) string {
+	newIcon, err := icon.FromBytes([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"/>`))
+	NoError(testing, err)
+	_, err = icon.NewDatabaseStore(database).Save(context.Background(), newIcon)
+	NoError(testing, err)
+	return newIcon.ID
+}
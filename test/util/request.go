@@ -0,0 +1,30 @@
+package util
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"habitgobackend/cmd/api/resource/common/identity"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewRequest builds a request for calling a handler directly, as if chi had routed it with the URL params and
+// the auth middleware had authenticated userID.
+func NewRequest(method string, target string, body string, params map[string]string, userID uuid.UUID) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, target, reader)
+
+	routeContext := chi.NewRouteContext()
+	for key, value := range params {
+		routeContext.URLParams.Add(key, value)
+	}
+
+	ctx := context.WithValue(request.Context(), chi.RouteCtxKey, routeContext)
+	return request.WithContext(identity.WithUserID(ctx, userID))
+}
@@ -28,17 +28,20 @@ func HabitsEqual(t *testing.T, actual habit.Habits, expected habit.Habits) {
 		if actualHabit.ID != expectedHabit.ID {
 			t.Errorf("Habit %d: ID mismatch. Got %v, expected %v", i, actualHabit.ID, expectedHabit.ID)
 		}
+		if actualHabit.UserID != expectedHabit.UserID {
+			t.Errorf("Habit %d: UserID mismatch. Got %v, expected %v", i, actualHabit.UserID, expectedHabit.UserID)
+		}
 		if actualHabit.Description != expectedHabit.Description {
 			t.Errorf("Habit %d: Description mismatch. Got %v, expected %v", i, actualHabit.Description, expectedHabit.Description)
 		}
 		if actualHabit.ColourHex != expectedHabit.ColourHex {
 			t.Errorf("Habit %d: ColourHex mismatch. Got %v, expected %v", i, actualHabit.ColourHex, expectedHabit.ColourHex)
 		}
-		if actualHabit.IconBase64 != expectedHabit.IconBase64 {
-			t.Errorf("Habit %d: IconBase64 mismatch. Got %v, expected %v", i, actualHabit.IconBase64, expectedHabit.IconBase64)
+		if actualHabit.IconID != expectedHabit.IconID {
+			t.Errorf("Habit %d: IconID mismatch. Got %v, expected %v", i, actualHabit.IconID, expectedHabit.IconID)
 		}
-		if actualHabit.ModeType != expectedHabit.ModeType {
-			t.Errorf("Habit %d: ModeType mismatch. Got %v, expected %v", i, actualHabit.ModeType, expectedHabit.ModeType)
+		if actualHabit.Schedule != expectedHabit.Schedule {
+			t.Errorf("Habit %d: Schedule mismatch. Got %v, expected %v", i, actualHabit.Schedule, expectedHabit.Schedule)
 		}
 	}
 }